@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"bytes"
+	encjson "encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/izern/zf/codec"
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// 差异的类型
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// Change 两个文档之间的一处差异
+type Change struct {
+	// Type added|removed|changed
+	Type string
+	// Path 差异所在的路径
+	Path string
+	// Old 原值，added时为nil
+	Old interface{}
+	// New 新值，removed时为nil
+	New interface{}
+}
+
+// ToValue 转换为可以按任意格式输出的object，added不包含old，removed不包含new
+func (c Change) ToValue() map[string]interface{} {
+	res := map[string]interface{}{
+		"type": c.Type,
+		"path": c.Path,
+	}
+	if c.Type != DiffAdded {
+		res["old"] = c.Old
+	}
+	if c.Type != DiffRemoved {
+		res["new"] = c.New
+	}
+	return res
+}
+
+// String 按行输出差异，+ 为新增，- 为删除，~ 为修改，值按紧凑的json输出
+func (c Change) String() string {
+	switch c.Type {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, diffValueString(c.New))
+	case DiffRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, diffValueString(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, diffValueString(c.Old), diffValueString(c.New))
+	}
+}
+
+func diffValueString(v interface{}) string {
+	opts := codec.DefaultMarshalOptions()
+	opts.Compact = true
+	opts.EscapeHTML = false
+	text, err := (&json.JSONCodec{}).MarshalWithOptions(v, opts)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(text)
+}
+
+// Diff 按语义比较两个文档，忽略键顺序和数字写法，object按键排序比较，数组按下标比较
+func Diff(a interface{}, b interface{}) ([]Change, types.ZfError) {
+	var changes []Change
+	err := diffValue(a, b, ".", &changes)
+	return changes, err
+}
+
+func diffValue(a interface{}, b interface{}, path string, changes *[]Change) types.ZfError {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			aItem, inA := av[k]
+			bItem, inB := bv[k]
+			switch {
+			case !inB:
+				*changes = append(*changes, Change{Type: DiffRemoved, Path: util.ChildPath(path, k), Old: aItem})
+			case !inA:
+				*changes = append(*changes, Change{Type: DiffAdded, Path: util.ChildPath(path, k), New: bItem})
+			default:
+				if err := diffValue(aItem, bItem, util.ChildPath(path, k), changes); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			switch {
+			case i >= len(bv):
+				*changes = append(*changes, Change{Type: DiffRemoved, Path: util.IndexPath(path, i), Old: av[i]})
+			case i >= len(av):
+				*changes = append(*changes, Change{Type: DiffAdded, Path: util.IndexPath(path, i), New: bv[i]})
+			default:
+				if err := diffValue(av[i], bv[i], util.IndexPath(path, i), changes); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	equal, err := Equal(a, b)
+	if err != nil {
+		return err
+	}
+	if !equal {
+		*changes = append(*changes, Change{Type: DiffChanged, Path: path, Old: a, New: b})
+	}
+	return nil
+}
+
+// Equal 按语义比较两个值，数字按数值比较，如 1、1.0、1e0 相等，其他值按RFC 8785规范化后比较
+func Equal(a interface{}, b interface{}) (bool, types.ZfError) {
+	if x, ok := toRat(a); ok {
+		y, ok := toRat(b)
+		if !ok {
+			return false, nil
+		}
+		if isFloat(a) || isFloat(b) {
+			// 小数按float64比较，yaml中的0.1与json中的0.1相等
+			f, _ := x.Float64()
+			g, _ := y.Float64()
+			return f == g, nil
+		}
+		return x.Cmp(y) == 0, nil
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false, nil
+		}
+		for k, item := range av {
+			other, ok := bv[k]
+			if !ok {
+				return false, nil
+			}
+			if equal, err := Equal(item, other); err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false, nil
+		}
+		for i := range av {
+			if equal, err := Equal(av[i], bv[i]); err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if f, ok := a.(float64); ok {
+		// NaN和Inf
+		g, ok := b.(float64)
+		return ok && (f == g || f != f && g != g), nil
+	}
+	x, err := json.Canonicalize(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := json.Canonicalize(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(x, y), nil
+}
+
+// toRat 将数字转换为精确的有理数，不是数字时返回false
+func toRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int8:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int16:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	case uint:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint8:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint16:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint32:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint64:
+		return new(big.Rat).SetUint64(n), true
+	case float32:
+		return floatRat(float64(n))
+	case float64:
+		return floatRat(n)
+	case encjson.Number:
+		return new(big.Rat).SetString(string(n))
+	default:
+		return nil, false
+	}
+}
+
+func isFloat(v interface{}) bool {
+	switch v.(type) {
+	case float32, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func floatRat(f float64) (*big.Rat, bool) {
+	r := new(big.Rat)
+	if r.SetFloat64(f) == nil {
+		// NaN和Inf无法转换
+		return nil, false
+	}
+	return r, true
+}
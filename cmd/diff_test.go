@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	encjson "encoding/json"
+	"fmt"
+	"github.com/izern/zf/codec/json"
+	yaml2 "github.com/izern/zf/codec/yaml"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_Diff(t *testing.T) {
+	a, err := (&yaml2.YamlCodec{}).Unmarshal([]byte("port: 7890\nratio: 0.1\nmode: rule\nlist: [1, 2, 3]\nobj: {x: 1}\n"))
+	assert.Nil(t, err)
+	b, err := (&json.JSONCodec{}).Unmarshal([]byte(`{"ratio":0.10,"port":7891,"list":[1,2],"obj":{"x":1.0,"y":"z"}}`))
+	assert.Nil(t, err)
+
+	changes, err := Diff(a, b)
+	assert.Nil(t, err)
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	fmt.Println(lines)
+	assert.Equal(t, []string{
+		`- .list[2]: 3`,
+		`- .mode: "rule"`,
+		`+ .obj.y: "z"`,
+		`~ .port: 7890 -> 7891`,
+	}, lines)
+	assert.Equal(t, map[string]interface{}{"type": DiffRemoved, "path": ".mode", "old": "rule"}, changes[1].ToValue())
+
+	changes, err = Diff(a, a)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = Diff([]interface{}{1}, map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Change{{Type: DiffChanged, Path: ".", Old: []interface{}{1}, New: map[string]interface{}{}}}, changes)
+
+	// 键名中的 . [ ] 按路径语法转义，输出的路径可以直接用于get
+	changes, err = Diff(map[string]interface{}{"x": map[string]interface{}{"k.z": 1, "a[0]": 1}}, map[string]interface{}{"x": map[string]interface{}{"k.z": 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, `- .x.a\[0\]: 1`, changes[0].String())
+	assert.Equal(t, `~ .x.k\.z: 1 -> 2`, changes[1].String())
+	handler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
+	v, err := handler.GetValues(0, math.MaxUint32, changes[1].Path, `{"x":{"k.z":1}}`)
+	assert.Nil(t, err)
+	assert.Equal(t, encjson.Number("1"), v)
+}
+
+func Test_Equal(t *testing.T) {
+	cases := []struct {
+		a, b  interface{}
+		equal bool
+	}{
+		{int64(1), encjson.Number("1.0"), true},
+		{uint64(18446744073709551615), encjson.Number("18446744073709551615"), true},
+		{encjson.Number("9007199254740993"), encjson.Number("9007199254740992"), false},
+		{0.1, encjson.Number("0.1"), true},
+		{"1", int64(1), false},
+		{nil, nil, true},
+		{nil, false, false},
+		{[]interface{}{1, "a"}, []interface{}{int64(1), "a"}, true},
+		{map[string]interface{}{"a": 1}, map[string]interface{}{"b": 1}, false},
+	}
+	for _, c := range cases {
+		equal, err := Equal(c.a, c.b)
+		assert.Nil(t, err)
+		assert.Equal(t, c.equal, equal, "%v == %v", c.a, c.b)
+	}
+}
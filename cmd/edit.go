@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// Edit 解析一次文本后按顺序执行所有操作并输出，任何一个操作失败时文档恢复原状并返回错误
+func (receiver *Handler) Edit(ops []types.EditOp, text string) (string, types.ZfError) {
+	if err := receiver.parseAndStore(text); err != nil {
+		return "", err
+	}
+	backup := util.DeepCopy(receiver.Value).(map[string]interface{})
+	for i, op := range ops {
+		if err := receiver.editAt(op); err != nil {
+			receiver.Value = backup
+			return "", types.NewEditError(i, op.Op, op.Path, err.Error().Error())
+		}
+	}
+	return receiver.PrintToString()
+}
+
+func (receiver *Handler) editAt(op types.EditOp) types.ZfError {
+	switch op.Op {
+	case "move", "copy":
+		return receiver.transferAt(op.Path, op.To, op.Op == "move")
+	}
+	paths, err := parseRootPath(op.Path)
+	if err != nil {
+		return err
+	}
+	switch op.Op {
+	case "set":
+		return receiver.setAt(paths[1:], util.DeepCopy(op.Value), op.Create)
+	case "append":
+		return receiver.appendAt(paths[1:], op.Key, op.Index, util.DeepCopy(op.Value))
+	case "delete":
+		if len(paths) < 2 {
+			return types.NewUnSupportError("不能删除根节点")
+		}
+		if _, err = receiver.resolveTargets(paths[1:], false); err != nil {
+			return err
+		}
+		return receiver.removeAt(paths[1:])
+	case "rename":
+		return receiver.renameAt(paths, op.To)
+	case "merge":
+		opts := DefaultMergeOptions()
+		if op.Array != "" {
+			opts.Array = op.Array
+		}
+		if op.Null != "" {
+			opts.Null = op.Null
+		}
+		targets, err := receiver.resolveTargets(paths[1:], true)
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			merged, err := Merge(target.get(), util.DeepCopy(op.Value), opts)
+			if err != nil {
+				return err
+			}
+			target.set(merged)
+		}
+		return nil
+	default:
+		return types.NewUnSupportError(fmt.Sprintf("edit操作%s，可选值为%s", op.Op, strings.Join(editOps, "|")))
+	}
+}
+
+// editOps 支持的操作
+var editOps = []string{"set", "append", "delete", "rename", "move", "copy", "merge"}
+
+// editArgs 每个操作的参数，命令行表达式按此顺序解析
+var editArgs = map[string][]string{
+	"set":    {"path", "value"},
+	"append": {"path", "value"},
+	"delete": {"path"},
+	"rename": {"path", "to"},
+	"move":   {"from", "to"},
+	"copy":   {"from", "to"},
+	"merge":  {"path", "value"},
+}
+
+// ParseEditScript 解析edit脚本，脚本为操作的数组，如 [{op: set, path: .a, value: 1}, {op: move, from: .a, to: .b}]
+// create为没有指定create的操作使用的默认值
+func ParseEditScript(script interface{}, create bool) ([]types.EditOp, types.ZfError) {
+	items, ok := script.([]interface{})
+	if !ok {
+		scriptType, _ := types.GetType(script)
+		return nil, types.NewUnSupportError(fmt.Sprintf("edit脚本应为array，实际为%s", scriptType))
+	}
+	ops := make([]types.EditOp, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, types.NewEditError(i, "", "", "操作应为object")
+		}
+		op, cause := parseEditOp(m, create)
+		if cause != "" {
+			return nil, types.NewEditError(i, op.Op, op.Path, cause)
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+func parseEditOp(m map[string]interface{}, create bool) (types.EditOp, string) {
+	op := types.EditOp{Create: create}
+	op.Op, _ = m["op"].(string)
+	args, ok := editArgs[op.Op]
+	if !ok {
+		return op, fmt.Sprintf("不支持的操作，可选值为%s", strings.Join(editOps, "|"))
+	}
+	for _, arg := range args {
+		v, exists := m[arg]
+		if !exists {
+			return op, "缺少" + arg
+		}
+		if arg == "value" {
+			op.Value = v
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			return op, arg + "应为字符串"
+		}
+		switch arg {
+		case "path", "from":
+			op.Path = s
+		case "to":
+			op.To = s
+		}
+	}
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{{"key", &op.Key}, {"array", &op.Array}, {"null", &op.Null}} {
+		if v, exists := m[field.name]; exists {
+			s, ok := v.(string)
+			if !ok {
+				return op, field.name + "应为字符串"
+			}
+			*field.value = s
+		}
+	}
+	op.Index = math.MaxInt16
+	if v, exists := m["index"]; exists {
+		index, err := strconv.ParseUint(fmt.Sprint(v), 10, 32)
+		if err != nil {
+			return op, "index应为非负整数"
+		}
+		op.Index = uint(index)
+	}
+	if v, exists := m["create"]; exists {
+		create, ok := v.(bool)
+		if !ok {
+			return op, "create应为bool"
+		}
+		op.Create = create
+	}
+	return op, ""
+}
+
+// ParseEditExpression 解析命令行的edit表达式，如 set .a 1、delete .b、rename .a b、move .a .b
+// 值为表达式剩余的部分，按当前格式解析
+func ParseEditExpression(expr string, typeCmd types.TypeCommand) (types.EditOp, types.ZfError) {
+	name, rest := splitEditToken(expr)
+	op := types.EditOp{Op: name, Index: math.MaxInt16}
+	args, ok := editArgs[name]
+	if !ok {
+		return op, types.NewUnSupportError(fmt.Sprintf("edit操作%s，可选值为%s", name, strings.Join(editOps, "|")))
+	}
+	for _, arg := range args {
+		var token string
+		if arg == "value" {
+			token, rest = rest, ""
+		} else {
+			token, rest = splitEditToken(rest)
+		}
+		if token == "" {
+			return op, types.NewFormatError(fmt.Sprintf("%s 缺少%s", expr, arg), "edit表达式")
+		}
+		switch arg {
+		case "path", "from":
+			op.Path = token
+		case "to":
+			op.To = token
+		case "value":
+			v, err := typeCmd.ParseValue(token)
+			if err != nil {
+				return op, err
+			}
+			op.Value = v
+		}
+	}
+	if rest != "" {
+		return op, types.NewFormatError(fmt.Sprintf("%s 多余的参数%s", expr, rest), "edit表达式")
+	}
+	return op, nil
+}
+
+// splitEditToken 返回第一个以空白分隔的参数和剩余的内容
+func splitEditToken(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"github.com/izern/zf/codec/json"
+	yaml2 "github.com/izern/zf/codec/yaml"
+	"github.com/izern/zf/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_Edit(t *testing.T) {
+	handler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
+	doc := `{"a":{"b":1},"l":[{"n":1},{"n":2}],"m":"x"}`
+
+	var ops []types.EditOp
+	for _, expr := range []string{
+		`set .a.c {"d":1}`,
+		`set .l[].t [1]`,
+		`append .l[0].t 2`,
+		`delete .m`,
+		`rename .a.b bb`,
+		`copy .a .z`,
+		`move .l[1] .y`,
+		`merge .a {"c":{"e":2}}`,
+	} {
+		op, err := ParseEditExpression(expr, handler)
+		assert.Nil(t, err, expr)
+		ops = append(ops, op)
+	}
+	result, err := handler.Edit(ops, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"bb":1,"c":{"d":1,"e":2}},"l":[{"n":1,"t":[1,2]}],"y":{"n":2,"t":[1]},"z":{"bb":1,"c":{"d":1}}}`, result)
+
+	// 任何一个操作失败时返回出错的操作
+	ops = append(ops[:1], types.EditOp{Op: "delete", Path: ".missing"})
+	_, err = handler.Edit(ops, doc)
+	assert.NotNil(t, err)
+	assert.Equal(t, "edit[1] (delete .missing)执行失败: 找不到键:missing", err.Error().Error())
+
+	_, err = ParseEditExpression("set .a", handler)
+	assert.NotNil(t, err)
+	_, err = ParseEditExpression("rename .a b c", handler)
+	assert.NotNil(t, err)
+	_, err = ParseEditExpression("frob .a", handler)
+	assert.NotNil(t, err)
+}
+
+func Test_ParseEditScript(t *testing.T) {
+	script, err := (&yaml2.YamlCodec{}).Unmarshal([]byte(`
+- {op: set, path: .a.b, value: 1, create: true}
+- {op: append, path: .l, value: x, index: 0}
+- {op: move, from: .a, to: .c}
+- {op: merge, path: ., value: {l: [y]}, array: append}
+`))
+	assert.Nil(t, err)
+	ops, err := ParseEditScript(script, false)
+	assert.Nil(t, err)
+	assert.Equal(t, types.EditOp{Op: "append", Path: ".l", Value: "x", Index: 0}, ops[1])
+	assert.Equal(t, ".a", ops[2].Path)
+
+	handler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
+	result, err := handler.Edit(ops, `{"l":[]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"c":{"b":1},"l":["x","y"]}`, result)
+
+	for _, text := range []string{`{op: set}`, `[{op: set, path: .a}]`, `[{op: rename, path: .a, to: 1}]`, `[{op: append, path: .a, value: 1, index: -1}]`} {
+		script, err = (&yaml2.YamlCodec{}).Unmarshal([]byte(text))
+		assert.Nil(t, err)
+		_, err = ParseEditScript(script, false)
+		assert.NotNil(t, err, text)
+	}
+
+	// 脚本中指定的create优先于默认值
+	script, err = (&yaml2.YamlCodec{}).Unmarshal([]byte(`[{op: set, path: .a.b, value: 1, create: false}, {op: set, path: .c.d, value: 2}]`))
+	assert.Nil(t, err)
+	ops, err = ParseEditScript(script, true)
+	assert.Nil(t, err)
+	assert.False(t, ops[0].Create)
+	assert.True(t, ops[1].Create)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// GrepOptions grep的匹配范围和过滤条件
+type GrepOptions struct {
+	// Keys 匹配object的键名
+	Keys bool
+	// Values 匹配字符串、数字、bool、null的值，数字和bool按json文本匹配
+	Values bool
+	// IgnoreCase 忽略大小写
+	IgnoreCase bool
+	// Types 只保留这些类型的值，为空时不过滤
+	Types []types.ValueType
+}
+
+// Validate 检查类型过滤条件，Keys和Values都为false时同时匹配键和值
+func (opts *GrepOptions) Validate() types.ZfError {
+	for _, t := range opts.Types {
+		switch t {
+		case types.String, types.Number, types.Bool, types.Null, types.Object, types.Array:
+		default:
+			return types.NewUnSupportError(fmt.Sprintf("类型%s，可选值为string|number|bool|null|object|array", t))
+		}
+	}
+	return nil
+}
+
+// GrepMatch 一处匹配的路径和值，匹配键时值为键对应的值
+type GrepMatch struct {
+	Path  string
+	Value interface{}
+}
+
+// ToValue 转换为可以按任意格式输出的object
+func (m GrepMatch) ToValue() map[string]interface{} {
+	return map[string]interface{}{"path": m.Path, "value": m.Value}
+}
+
+// String 按 路径: 值 输出，值按紧凑的json输出
+func (m GrepMatch) String() string {
+	return fmt.Sprintf("%s: %s", m.Path, diffValueString(m.Value))
+}
+
+// Grep 遍历整个文档，返回键名或值匹配正则的路径，object按键排序遍历
+func Grep(doc interface{}, pattern string, opts GrepOptions) ([]GrepMatch, types.ZfError) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, e := regexp.Compile(pattern)
+	if e != nil {
+		return nil, types.NewFormatError(fmt.Sprintf("%s: %v", pattern, e), "正则表达式")
+	}
+	if !opts.Keys && !opts.Values {
+		opts.Keys, opts.Values = true, true
+	}
+	var matches []GrepMatch
+	err := grepValue(doc, ".", re, &opts, &matches)
+	return matches, err
+}
+
+func grepValue(v interface{}, path string, re *regexp.Regexp, opts *GrepOptions, matches *[]GrepMatch) types.ZfError {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			item := val[k]
+			matched := false
+			if opts.Keys && re.MatchString(k) {
+				ok, err := opts.accept(item)
+				if err != nil {
+					return err
+				}
+				if ok {
+					*matches = append(*matches, GrepMatch{Path: util.ChildPath(path, k), Value: item})
+					matched = true
+				}
+			}
+			if matched && !isContainer(item) {
+				// 键已经匹配时不再重复匹配同一个值
+				continue
+			}
+			if err := grepValue(item, util.ChildPath(path, k), re, opts, matches); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			if err := grepValue(item, util.IndexPath(path, i), re, opts, matches); err != nil {
+				return err
+			}
+		}
+	default:
+		if !opts.Values {
+			return nil
+		}
+		ok, err := opts.accept(v)
+		if err != nil || !ok {
+			return err
+		}
+		text, isString := v.(string)
+		if !isString {
+			text = diffValueString(v)
+		}
+		if re.MatchString(text) {
+			*matches = append(*matches, GrepMatch{Path: path, Value: v})
+		}
+	}
+	return nil
+}
+
+// accept 值的类型是否满足过滤条件
+func (opts *GrepOptions) accept(v interface{}) (bool, types.ZfError) {
+	if len(opts.Types) == 0 {
+		return true, nil
+	}
+	valueType, err := types.GetType(v)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range opts.Types {
+		if t == valueType {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isContainer(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	encjson "encoding/json"
+	"fmt"
+	yaml2 "github.com/izern/zf/codec/yaml"
+	"github.com/izern/zf/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_Grep(t *testing.T) {
+	doc, err := (&yaml2.YamlCodec{}).Unmarshal([]byte(`
+port: 443
+server: ssl.tcpbbr.net
+proxies:
+  - {name: hk, server: ssl.tcpbbr.net, port: 443, udp: true}
+  - {name: us, server: 1.2.3.4, port: 8443, opts: {Server-Name: SSL.tcpbbr.net}}
+`))
+	assert.Nil(t, err)
+
+	matches, err := Grep(doc, `tcpbbr\.net`, GrepOptions{})
+	assert.Nil(t, err)
+	var lines []string
+	for _, m := range matches {
+		lines = append(lines, m.String())
+	}
+	fmt.Println(lines)
+	assert.Equal(t, []string{
+		`.proxies[0].server: "ssl.tcpbbr.net"`,
+		`.proxies[1].opts.Server-Name: "SSL.tcpbbr.net"`,
+		`.server: "ssl.tcpbbr.net"`,
+	}, lines)
+
+	// 键和值同时匹配时只输出一次，键匹配到object时继续搜索其中的值
+	matches, err = Grep(doc, `(?i)server`, GrepOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []GrepMatch{
+		{Path: ".proxies[0].server", Value: "ssl.tcpbbr.net"},
+		{Path: ".proxies[1].opts.Server-Name", Value: "SSL.tcpbbr.net"},
+		{Path: ".proxies[1].server", Value: "1.2.3.4"},
+		{Path: ".server", Value: "ssl.tcpbbr.net"},
+	}, matches)
+
+	matches, err = Grep(doc, `^ssl`, GrepOptions{Values: true, IgnoreCase: true})
+	assert.Nil(t, err)
+	assert.Len(t, matches, 3)
+
+	matches, err = Grep(doc, `^443$`, GrepOptions{Values: true, Types: []types.ValueType{types.Number}})
+	assert.Nil(t, err)
+	assert.Equal(t, []GrepMatch{{Path: ".port", Value: encjson.Number("443")}, {Path: ".proxies[0].port", Value: encjson.Number("443")}}, matches)
+
+	matches, err = Grep(doc, `^(opts|udp)$`, GrepOptions{Keys: true, Types: []types.ValueType{types.Object}})
+	assert.Nil(t, err)
+	assert.Equal(t, []GrepMatch{{Path: ".proxies[1].opts", Value: map[string]interface{}{"Server-Name": "SSL.tcpbbr.net"}}}, matches)
+	assert.Equal(t, map[string]interface{}{"path": ".proxies[1].opts", "value": matches[0].Value}, matches[0].ToValue())
+
+	// 键名中的 . 按路径语法转义
+	matches, err = Grep(map[string]interface{}{"server": map[string]interface{}{"a.b": 1}}, `a\.b`, GrepOptions{Keys: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []GrepMatch{{Path: `.server.a\.b`, Value: 1}}, matches)
+
+	matches, err = Grep(doc, `true`, GrepOptions{Values: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []GrepMatch{{Path: ".proxies[0].udp", Value: true}}, matches)
+
+	_, err = Grep(doc, `(`, GrepOptions{})
+	assert.NotNil(t, err)
+	_, err = Grep(doc, `a`, GrepOptions{Types: []types.ValueType{"int"}})
+	assert.Equal(t, "不支持的操作：类型int，可选值为string|number|bool|null|object|array", err.Error().Error())
+}
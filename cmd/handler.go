@@ -6,6 +6,7 @@ import (
 	"sort"
 
 	"github.com/izern/zf/codec"
+	"github.com/izern/zf/codec/json"
 	"github.com/izern/zf/types"
 	"github.com/izern/zf/util"
 )
@@ -22,6 +23,8 @@ type Handler struct {
 	Unmarshaler codec.Unmarshaler
 	Type        string
 	Value       map[string]interface{}
+	// Options 输出时使用的格式选项
+	Options codec.MarshalOptions
 }
 
 func NewHandler(marshaler codec.Marshaler, unmarshaler codec.Unmarshaler, typeStr string) *Handler {
@@ -29,6 +32,7 @@ func NewHandler(marshaler codec.Marshaler, unmarshaler codec.Unmarshaler, typeSt
 		Marshaler:   marshaler,
 		Unmarshaler: unmarshaler,
 		Type:        typeStr,
+		Options:     codec.DefaultMarshalOptions(),
 	}
 }
 
@@ -36,6 +40,14 @@ func (receiver Handler) GetCurrType() string {
 	return receiver.Type
 }
 
+// IsBinary 当前格式输出是否为二进制内容
+func (receiver Handler) IsBinary() bool {
+	if c, ok := receiver.Marshaler.(codec.Codec); ok {
+		return c.GetInfo().Capabilities.IsBinary
+	}
+	return false
+}
+
 // parseAndStore parses text and stores the result in the handler's Value field
 // This centralizes the parsing logic and reduces duplication
 func (receiver *Handler) parseAndStore(text string) types.ZfError {
@@ -74,33 +86,52 @@ func (receiver *Handler) PrintToString() (string, types.ZfError) {
 		return "", types.NewUnSupportError("未初始化，无法输出内容")
 	}
 	
-	var content interface{}
-	if len(receiver.Value) == 1 && receiver.Value[""] != nil {
-		// Single value, not an object
-		content = receiver.Value[""]
-	} else {
-		// Object or multiple values
-		content = receiver.Value
-	}
-	
-	bytes, err := receiver.Marshaler.Marshal(content)
+	bytes, err := receiver.Marshaler.MarshalWithOptions(receiver.rootValue(), receiver.Options)
 	if err != nil {
 		return "", err
 	}
 	return string(bytes), nil
 }
 
+// rootValue 返回解析后的根节点值，非object的内容保存在""键下
+func (receiver *Handler) rootValue() interface{} {
+	if len(receiver.Value) == 1 {
+		if v, ok := receiver.Value[""]; ok {
+			// Single value, not an object
+			return v
+		}
+	}
+	return receiver.Value
+}
+
+func (receiver *Handler) GetDocument() interface{} {
+	return receiver.rootValue()
+}
+
 func (receiver *Handler) Marshal(content interface{}) (string, types.ZfError) {
 	if content == nil {
 		return "", nil
 	}
-	res, err := receiver.Marshaler.Marshal(content)
+	res, err := receiver.Marshaler.MarshalWithOptions(content, receiver.Options)
 	if err != nil {
 		return "", err
 	}
 	return string(res), nil
 }
 
+// MarshalResult 同Marshal，content为nil时输出格式中的null，格式无法表示null时输出文本null
+func MarshalResult(typeCmd types.TypeCommand, content interface{}) (string, types.ZfError) {
+	if content != nil {
+		return typeCmd.Marshal(content)
+	}
+	if handler, ok := typeCmd.(*Handler); ok {
+		if res, err := handler.Marshaler.MarshalWithOptions(nil, handler.Options); err == nil {
+			return string(res), nil
+		}
+	}
+	return "null", nil
+}
+
 // validatePathAndParse centralizes path validation and text parsing
 func (receiver *Handler) validatePathAndParse(path, text string) ([]*types.Path, types.ZfError) {
 	err := receiver.parseAndStore(text)
@@ -108,15 +139,18 @@ func (receiver *Handler) validatePathAndParse(path, text string) ([]*types.Path,
 		return nil, err
 	}
 	
+	return parseRootPath(path)
+}
+
+// parseRootPath 解析以根节点开头的路径
+func parseRootPath(path string) ([]*types.Path, types.ZfError) {
 	paths, err := util.ParsePath(path)
 	if err != nil {
 		return nil, err
 	}
-	
 	if len(paths) < 1 || paths[0].Type != types.RootNode {
 		return nil, types.NewFormatError(path, "path")
 	}
-	
 	return paths, nil
 }
 
@@ -158,7 +192,7 @@ func (receiver *Handler) getValues(path string, text string) (interface{}, types
 		return nil, err
 	}
 	
-	return getValues(paths[1:], receiver.Value)
+	return getValues(paths[1:], receiver.rootValue())
 }
 
 // processArrayValue handles different array type conversions consistently
@@ -270,210 +304,542 @@ func (receiver *Handler) GetValues(from uint, to uint, path string, text string)
 	return res, nil
 }
 
+// ParseValue 按当前格式解析命令行传入的值
+func (receiver *Handler) ParseValue(value string) (interface{}, types.ZfError) {
+	return receiver.parseValueWithUnmarshaler(value)
+}
+
 // parseValueWithUnmarshaler centralizes value parsing logic
 func (receiver *Handler) parseValueWithUnmarshaler(value string) (interface{}, types.ZfError) {
+	if receiver.IsBinary() {
+		// 命令行传入的值是文本，二进制格式按json解析，解析失败则当作字符串
+		v, err := (&json.JSONCodec{}).Unmarshal([]byte(value))
+		if err != nil {
+			return value, nil
+		}
+		return v, nil
+	}
 	return receiver.Unmarshaler.Unmarshal([]byte(value))
 }
 
 func (receiver *Handler) Append(path string, key string, index uint, value string, text string) (string, types.ZfError) {
+	v, err := receiver.parseValueWithUnmarshaler(value)
+	if err != nil {
+		return "", err
+	}
+	return receiver.AppendParsed(path, key, index, v, text)
+}
+
+func (receiver *Handler) AppendParsed(path string, key string, index uint, v interface{}, text string) (string, types.ZfError) {
 	paths, err := receiver.validatePathAndParse(path, text)
 	if err != nil {
 		return "", err
 	}
-	
-	if len(paths) <= 1 {
-		return "", types.NewUnSupportError("路径最少要有两层，如 .a")
+	if err = receiver.appendAt(paths[1:], key, index, v); err != nil {
+		return "", err
 	}
+	return receiver.PrintToString()
+}
 
-	// 根据路径获取其父节点值
-	parentValue, e := getValues(paths[1:len(paths)-1], receiver.Value)
-	if e != nil {
-		return "", e
+// appendAt 对已解析文档中路径(不含根节点)对应的值追加内容
+func (receiver *Handler) appendAt(paths []*types.Path, key string, index uint, v interface{}) types.ZfError {
+	targets, err := receiver.resolveTargets(paths, true)
+	if err != nil {
+		return err
 	}
 
-	parentMap := parentValue.(map[string]interface{})
-	lastPath := paths[len(paths)-1]
+	vType, _ := types.GetType(v)
+	for i, target := range targets {
+		if i > 0 {
+			v = util.DeepCopy(v)
+		}
+		current := target.get()
+		currentType, _ := types.GetType(current)
+
+		switch currentType {
+		case types.Array:
+			array := current.([]interface{})
+			actualIndex := util.Min(len(array), int(index))
 
-	lastPathV := parentMap[lastPath.NodeKey]
-	lastPathVType, _ := types.GetType(lastPathV)
+			var appendV []interface{}
+			if types.Array == vType {
+				appendV = v.([]interface{})
+			} else {
+				appendV = []interface{}{v}
+			}
+			result := make([]interface{}, 0, len(array)+len(appendV))
+			result = append(result, array[:actualIndex]...)
+			result = append(result, appendV...)
+			result = append(result, array[actualIndex:]...)
+			target.set(result)
+
+		case types.Object:
+			obj := current.(map[string]interface{})
+
+			var vMap map[string]interface{}
+			switch v.(type) {
+			case map[string]interface{}:
+				vMap = v.(map[string]interface{})
+			case map[interface{}]interface{}:
+				vMap = util.ConvertMap2String(v.(map[interface{}]interface{}))
+			}
+			if vType == types.Object {
+				for k, vItem := range vMap {
+					obj[k] = vItem
+				}
+			} else {
+				if key == "" {
+					return types.NewUnSupportError("当前节点类别为object，必须指定key")
+				}
+				obj[key] = v
+			}
+		case types.Null:
+			target.set(v)
+		default:
+			target.set(fmt.Sprintf("%v%v", current, v))
+		}
+	}
+	return nil
+}
 
+func (receiver *Handler) SetValue(path string, value string, text string) (string, types.ZfError) {
 	v, err := receiver.parseValueWithUnmarshaler(value)
 	if err != nil {
 		return "", err
 	}
+	return receiver.SetParsed(path, v, false, text)
+}
 
-	vType, _ := types.GetType(v)
-
-	switch lastPathVType {
-	case types.Array:
-		lastPathArrayV := lastPathV.([]interface{})
-		actualIndex := util.Min(len(lastPathArrayV), int(index))
-
-		size := 1
-		var appendV []interface{}
-		if types.Array == vType {
-			appendV = v.([]interface{})
-			size = len(appendV)
-		} else {
-			appendV = []interface{}{v}
-		}
-		result := make([]interface{}, len(lastPathArrayV)+size)
+func (receiver *Handler) SetParsed(path string, v interface{}, create bool, text string) (string, types.ZfError) {
+	paths, err := receiver.validatePathAndParse(path, text)
+	if err != nil {
+		return "", err
+	}
+	if err = receiver.setAt(paths[1:], v, create); err != nil {
+		return "", err
+	}
+	return receiver.PrintToString()
+}
 
-		err = util.ArrayCopy(lastPathArrayV, 0, result, 0, actualIndex)
-		if err != nil {
-			return "", err
+// setAt 设置已解析文档中路径(不含根节点)对应的值
+func (receiver *Handler) setAt(paths []*types.Path, v interface{}, create bool) types.ZfError {
+	if create {
+		if root, ok := receiver.Value[""]; ok && len(receiver.Value) == 1 {
+			if root != nil {
+				rootType, _ := types.GetType(root)
+				return types.NewUnSupportError("根节点类型为" + string(rootType) + "，无法创建路径")
+			}
+			// 空文档
+			receiver.Value = make(map[string]interface{})
 		}
-		err = util.ArrayCopy(appendV, 0, result, actualIndex, size)
-		if err != nil {
-			return "", err
+		if err := createPath(receiver.Value, paths); err != nil {
+			return err
 		}
+	}
 
-		err = util.ArrayCopy(lastPathArrayV, actualIndex, result, actualIndex+size, len(lastPathArrayV)-actualIndex)
-		if err != nil {
-			return "", err
+	targets, err := receiver.resolveTargets(paths, true)
+	if err != nil {
+		return err
+	}
+	for i, target := range targets {
+		if i > 0 {
+			// 多个位置不共用同一个值，避免后续修改相互影响
+			v = util.DeepCopy(v)
 		}
+		target.set(v)
+	}
+	return nil
+}
 
-		parentMap[lastPath.NodeKey] = result
+// Rename 修改指定路径的键名，路径中包含 [] 时对所有元素生效
+func (receiver *Handler) Rename(path string, newKey string, text string) (string, types.ZfError) {
+	paths, err := receiver.validatePathAndParse(path, text)
+	if err != nil {
+		return "", err
+	}
+	if err = receiver.renameAt(paths, newKey); err != nil {
+		return "", err
+	}
+	return receiver.PrintToString()
+}
 
-	case types.Object:
-		lastPathMapV := lastPathV.(map[string]interface{})
+// renameAt 修改已解析文档中路径(含根节点)对应的键名
+func (receiver *Handler) renameAt(paths []*types.Path, newKey string) types.ZfError {
+	if newKey == "" {
+		return types.NewUnSupportError("新的键名不能为空")
+	}
+	last := paths[len(paths)-1]
+	if len(paths) < 2 || last.Type != types.NormalNode {
+		return types.NewUnSupportError("rename只支持object的键，如 .a.b")
+	}
 
-		var vMap map[string]interface{}
-		switch v.(type) {
-		case map[string]interface{}:
-			vMap = v.(map[string]interface{})
-		case map[interface{}]interface{}:
-			vMap = util.ConvertMap2String(v.(map[interface{}]interface{}))
+	parents, err := receiver.resolveTargets(paths[1:len(paths)-1], false)
+	if err != nil {
+		return err
+	}
+	for _, parent := range parents {
+		obj, ok := parent.get().(map[string]interface{})
+		if !ok {
+			parentType, _ := types.GetType(parent.get())
+			return types.NewUnSupportError(fmt.Sprintf("%s的父节点类型为%s，不是object", last.OriginValue, parentType))
 		}
-		if vType == types.Object {
-			for k, vItem := range vMap {
-				lastPathMapV[k] = vItem
-			}
-		} else {
-			if key == "" {
-				return "", types.NewUnSupportError("当前节点类别为object，必须指定key")
-			}
-			lastPathMapV[key] = v
+		v, exists := obj[last.NodeKey]
+		if !exists {
+			return types.NewKeyNotFoundError(last.NodeKey)
 		}
-	case types.Null:
-		parentMap[lastPath.NodeKey] = v
-	default:
-		parentMap[lastPath.NodeKey] = fmt.Sprintf("%v%v", lastPathV, v)
+		if newKey == last.NodeKey {
+			continue
+		}
+		if _, exists = obj[newKey]; exists {
+			return types.NewUnSupportError("键" + newKey + "已存在")
+		}
+		obj[newKey] = v
+		delete(obj, last.NodeKey)
 	}
+	return nil
+}
 
-	return receiver.PrintToString()
+// Move 将from路径的值移动到to路径，to的父节点不存在时自动创建
+func (receiver *Handler) Move(from string, to string, text string) (string, types.ZfError) {
+	return receiver.transfer(from, to, text, true)
 }
 
-func (receiver *Handler) SetValue(path string, value string, text string) (string, types.ZfError) {
-	paths, err := receiver.validatePathAndParse(path, text)
-	if err != nil {
+// Copy 将from路径的值复制到to路径，to的父节点不存在时自动创建
+func (receiver *Handler) Copy(from string, to string, text string) (string, types.ZfError) {
+	return receiver.transfer(from, to, text, false)
+}
+
+func (receiver *Handler) transfer(from string, to string, text string, remove bool) (string, types.ZfError) {
+	if err := receiver.parseAndStore(text); err != nil {
 		return "", err
 	}
-	
-	if len(paths) < 1 {
-		return "", types.NewUnSupportError("路径最少要有两层，如 .a")
+	if err := receiver.transferAt(from, to, remove); err != nil {
+		return "", err
 	}
+	return receiver.PrintToString()
+}
 
-	// 根据路径获取其父节点值
-	parentValue, e := getValues(paths[1:len(paths)-1], receiver.Value)
-	if e != nil {
-		return "", e
+// transferAt 在已解析文档中将from路径的值移动或复制到to路径
+func (receiver *Handler) transferAt(from string, to string, remove bool) types.ZfError {
+	fromPaths, err := parseRootPath(from)
+	if err != nil {
+		return err
 	}
-	
-	v, err := receiver.parseValueWithUnmarshaler(value)
+	toPaths, err := parseRootPath(to)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if len(fromPaths) < 2 || len(toPaths) < 2 {
+		return types.NewUnSupportError("不能移动或复制根节点")
 	}
 
-	e = receiver.setValue0(parentValue, v, 0, 0, paths)
-	if e != nil {
-		return "", e
+	sources, err := receiver.resolveTargets(fromPaths[1:], false)
+	if err != nil {
+		return err
+	}
+	if len(sources) != 1 {
+		return types.NewUnSupportError(fmt.Sprintf("%s对应%d个值，只支持单个值", from, len(sources)))
 	}
+	v := util.DeepCopy(sources[0].get())
 
-	return receiver.PrintToString()
+	if remove {
+		if samePath(fromPaths, toPaths) {
+			// 移动到原位置时不做修改
+			return nil
+		}
+		if isSubPath(fromPaths, toPaths) {
+			return types.NewUnSupportError("不能移动到自身的子路径:" + to)
+		}
+		if err = receiver.removeAt(fromPaths[1:]); err != nil {
+			return err
+		}
+	}
+	// 目标为数组下标时插入到该位置，后面的元素后移；同一数组内移动时下标按删除源值后的数组计算
+	inserted, err := receiver.insertAt(toPaths[1:], v)
+	if err != nil || inserted {
+		return err
+	}
+	return receiver.setAt(toPaths[1:], v, true)
 }
 
-func (receiver *Handler) setValue0(parentV interface{}, v interface{}, from uint, to uint, paths []*types.Path) types.ZfError {
-	switch parentV.(type) {
-	case map[string]interface{}:
-		parentMap := parentV.(map[string]interface{})
-		lastPath := paths[len(paths)-1]
-
-		lastPathV := parentMap[lastPath.NodeKey]
+// insertAt 在路径(不含根节点)对应的数组下标处插入值，数组不存在或下标超出数组长度时不做修改并返回false
+func (receiver *Handler) insertAt(paths []*types.Path, v interface{}) (bool, types.ZfError) {
+	last := paths[len(paths)-1]
+	if last.Type != types.IndexNode {
+		return false, nil
+	}
+	arrayPaths := make([]*types.Path, len(paths)-1, len(paths))
+	copy(arrayPaths, paths)
+	if last.NodeKey != "" {
+		arrayPaths = append(arrayPaths, &types.Path{Type: types.NormalNode, NodeKey: last.NodeKey, OriginValue: last.NodeKey})
+	}
+	containers, err := receiver.resolveTargets(arrayPaths, true)
+	if err != nil {
+		// 交给setAt创建路径或报错
+		return false, nil
+	}
+	arrays := make([][]interface{}, 0, len(containers))
+	for _, container := range containers {
+		array, ok := container.get().([]interface{})
+		if !ok || int(last.Index) > len(array) {
+			return false, nil
+		}
+		arrays = append(arrays, array)
+	}
+	for i, container := range containers {
+		if i > 0 {
+			v = util.DeepCopy(v)
+		}
+		array := arrays[i]
+		result := make([]interface{}, 0, len(array)+1)
+		result = append(result, array[:last.Index]...)
+		result = append(result, v)
+		result = append(result, array[last.Index:]...)
+		container.set(result)
+	}
+	return true, nil
+}
 
-		switch lastPath.Type {
-		case types.IndexNode:
-			err := receiver.setValue1(lastPathV, v, lastPath.Index, lastPath.Index+1)
-			if err != nil {
-				return err
+// removeAt 删除路径(不含根节点)对应的键或数组元素
+func (receiver *Handler) removeAt(paths []*types.Path) types.ZfError {
+	last := paths[len(paths)-1]
+	parents, err := receiver.resolveTargets(paths[:len(paths)-1], false)
+	if err != nil {
+		return err
+	}
+	for _, parent := range parents {
+		container := parent
+		if last.NodeKey != "" {
+			obj, ok := parent.get().(map[string]interface{})
+			if !ok {
+				parentType, _ := types.GetType(parent.get())
+				return types.NewUnSupportError(fmt.Sprintf("%s的父节点类型为%s，不是object", last.OriginValue, parentType))
 			}
-		case types.RangeNode:
-			err := receiver.setValue1(lastPathV, v, lastPath.From, lastPath.To)
-			if err != nil {
-				return err
+			if last.Type == types.NormalNode {
+				delete(obj, last.NodeKey)
+				continue
 			}
-		default:
-			parentMap[lastPath.NodeKey] = v
-		}
-	case []interface{}:
-		parentValue := parentV.([]interface{})
-		for _, m := range parentValue {
-			e := receiver.setValue0(m, v, from, to, paths)
-			if e != nil {
-				return e
+			key := last.NodeKey
+			container = target{
+				get: func() interface{} {
+					return obj[key]
+				},
+				set: func(v interface{}) {
+					obj[key] = v
+				},
 			}
 		}
-	case []map[string]interface{}:
-		parentValue := parentV.([]map[string]interface{})
-		for _, m := range parentValue {
-			e := receiver.setValue0(m, v, from, to, paths)
-			if e != nil {
-				return e
+
+		array, ok := container.get().([]interface{})
+		if !ok {
+			valueType, _ := types.GetType(container.get())
+			return types.NewUnSupportError(fmt.Sprintf("只支持array格式指定index，%s的类型为%s", last.OriginValue, valueType))
+		}
+		from, to := last.Index, last.Index+1
+		if last.Type == types.RangeNode {
+			from, to = last.From, last.To
+			if to == math.MaxInt16 {
+				to = uint(len(array))
 			}
 		}
-	default:
-		parentValueType, _ := types.GetType(parentV)
-		return types.NewUnSupportError(fmt.Sprintf("%s不支持的类型%s", paths[len(paths)-2].NodeKey, parentValueType))
+		if int(to) > len(array) {
+			return types.NewIndexOutOfBoundErrorFromSlice(array, last.OriginValue, int(to)-1)
+		}
+		result := make([]interface{}, 0, len(array)-int(to-from))
+		result = append(result, array[:from]...)
+		result = append(result, array[to:]...)
+		container.set(result)
 	}
 	return nil
+}
 
+// samePath 判断两个路径是否相同
+func samePath(a []*types.Path, b []*types.Path) bool {
+	return len(a) == len(b) && isPrefixPath(a, b)
 }
 
-func (receiver *Handler) setValue1(lastV interface{}, v interface{}, from uint, to uint) types.ZfError {
+// isSubPath 判断child是否在parent之下，路径相同时返回false
+func isSubPath(parent []*types.Path, child []*types.Path) bool {
+	return len(child) > len(parent) && isPrefixPath(parent, child)
+}
 
-	parentVType, _ := types.GetType(lastV)
-	if parentVType != types.Array {
-		return types.NewUnSupportError(fmt.Sprintf("只支持array格式指定index，当前节点类别为:%s", parentVType))
+// isPrefixPath 判断prefix是否为path开头的部分
+func isPrefixPath(prefix []*types.Path, path []*types.Path) bool {
+	if len(path) < len(prefix) {
+		return false
 	}
-	switch lastV.(type) {
-	case []map[string]interface{}:
-		array := lastV.([]map[string]interface{})
-		if int(from) > len(array)-1 {
-			return types.NewIndexOutOfBoundErrorFromMapSlice(array, "array", int(from))
+	for i := range prefix {
+		if !samePathNode(prefix[i], path[i]) {
+			return false
 		}
-		switch v.(type) {
-		case map[string]interface{}:
-			for i := from; i < to; i++ {
-				array[i] = v.(map[string]interface{})
+	}
+	return true
+}
+
+// samePathNode 按解析后的键、类型和下标比较路径节点，$.a 与 .a 视为相同
+func samePathNode(a *types.Path, b *types.Path) bool {
+	if a.Type != b.Type || a.NodeKey != b.NodeKey {
+		return false
+	}
+	switch a.Type {
+	case types.IndexNode:
+		return a.Index == b.Index
+	case types.RangeNode:
+		return a.From == b.From && a.To == b.To
+	default:
+		return true
+	}
+}
+
+// target 路径指向的一个位置，set 会把值写回父节点
+type target struct {
+	get func() interface{}
+	set func(v interface{})
+}
+
+// resolveTargets 从根节点开始按路径查找所有目标位置，父节点可以是object或array的任意组合
+// 范围路径如 [] 会对应多个位置，allowMissing为true时最后一个键可以不存在
+func (receiver *Handler) resolveTargets(paths []*types.Path, allowMissing bool) ([]target, types.ZfError) {
+	targets := []target{{
+		get: receiver.rootValue,
+		set: func(v interface{}) {
+			if obj, ok := v.(map[string]interface{}); ok {
+				receiver.Value = obj
+				return
 			}
-		case map[interface{}]interface{}:
-			for i := from; i < to; i++ {
-				array[i] = util.ConvertMap2String(v.(map[interface{}]interface{}))
+			receiver.Value = map[string]interface{}{"": v}
+		},
+	}}
+	for i, p := range paths {
+		last := i == len(paths)-1
+		next := make([]target, 0, len(targets))
+		for _, t := range targets {
+			cur := t.get()
+			if p.NodeKey != "" {
+				obj, ok := cur.(map[string]interface{})
+				if !ok {
+					curType, _ := types.GetType(cur)
+					return nil, types.NewUnSupportError(fmt.Sprintf("%s的父节点类型为%s，不是object", p.OriginValue, curType))
+				}
+				key := p.NodeKey
+				if _, exists := obj[key]; !exists && !(allowMissing && last && p.Type == types.NormalNode) {
+					return nil, types.NewKeyNotFoundError(key)
+				}
+				t = target{
+					get: func() interface{} {
+						return obj[key]
+					},
+					set: func(v interface{}) {
+						obj[key] = v
+					},
+				}
+			}
+
+			switch p.Type {
+			case types.IndexNode, types.RangeNode:
+				array, ok := t.get().([]interface{})
+				if !ok {
+					curType, _ := types.GetType(t.get())
+					return nil, types.NewUnSupportError(fmt.Sprintf("只支持array格式指定index，%s的类型为%s", p.OriginValue, curType))
+				}
+				from, to := p.Index, p.Index+1
+				if p.Type == types.RangeNode {
+					from, to = p.From, p.To
+					if to == math.MaxInt16 {
+						to = uint(len(array))
+					}
+				}
+				if int(to) > len(array) {
+					return nil, types.NewIndexOutOfBoundErrorFromSlice(array, p.OriginValue, int(to)-1)
+				}
+				for index := from; index < to; index++ {
+					index := index
+					next = append(next, target{
+						get: func() interface{} {
+							return array[index]
+						},
+						set: func(v interface{}) {
+							array[index] = v
+						},
+					})
+				}
+			default:
+				next = append(next, t)
 			}
-		default:
-			return types.NewUnSupportError(fmt.Sprintf("传入参数格式与指定节点格式不符，指定节点格式为%s", types.Object))
 		}
-	case []interface{}:
-		switch v.(type) {
-		case interface{}, nil:
-			array := lastV.([]interface{})
-			for i := from; i < to; i++ {
-				array[i] = v
+		targets = next
+	}
+	return targets, nil
+}
+
+// createPath 按路径创建缺失的object，下一个节点为下标时创建array，下标超出长度时用null补齐
+// 最后一个节点的值由调用方设置，范围路径不会创建元素
+func createPath(root map[string]interface{}, paths []*types.Path) types.ZfError {
+	var cur interface{} = root
+	// set 替换当前节点在父节点中的值，array扩容后需要写回
+	set := func(v interface{}) {}
+	for i, p := range paths {
+		last := i == len(paths)-1
+		if p.NodeKey != "" {
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				curType, _ := types.GetType(cur)
+				return types.NewUnSupportError(fmt.Sprintf("%s的父节点类型为%s，无法创建", p.OriginValue, curType))
+			}
+			key := p.NodeKey
+			child := obj[key]
+			if child == nil {
+				if p.Type == types.NormalNode {
+					if last {
+						return nil
+					}
+					child = newContainer(paths[i+1])
+				} else {
+					child = make([]interface{}, 0)
+				}
+				obj[key] = child
+			}
+			cur = child
+			set = func(v interface{}) {
+				obj[key] = v
 			}
-		default:
-			return types.NewUnSupportError(fmt.Sprintf("传入参数格式与指定节点格式不符，指定节点格式为普通节点"))
+		}
+
+		switch p.Type {
+		case types.IndexNode:
+			arr, ok := cur.([]interface{})
+			if !ok {
+				curType, _ := types.GetType(cur)
+				return types.NewUnSupportError(fmt.Sprintf("只支持array格式指定index，%s的类型为%s", p.OriginValue, curType))
+			}
+			if int(p.Index) >= len(arr) {
+				grown := make([]interface{}, p.Index+1)
+				copy(grown, arr)
+				arr = grown
+				set(arr)
+			}
+			if last {
+				return nil
+			}
+			if arr[p.Index] == nil {
+				arr[p.Index] = newContainer(paths[i+1])
+			}
+			cur = arr[p.Index]
+			index := p.Index
+			set = func(v interface{}) {
+				arr[index] = v
+			}
+		case types.RangeNode:
+			return nil
 		}
 	}
 	return nil
+}
 
+// newContainer 根据下一个路径节点创建object或array
+func newContainer(next *types.Path) interface{} {
+	if next.NodeKey == "" && (next.Type == types.IndexNode || next.Type == types.RangeNode) {
+		return make([]interface{}, 0)
+	}
+	return make(map[string]interface{})
 }
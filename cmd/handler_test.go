@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	encjson "encoding/json"
 	"github.com/izern/zf/codec/json"
 	"github.com/izern/zf/codec/toml"
 	yaml2 "github.com/izern/zf/codec/yaml"
+	"github.com/izern/zf/query"
 	"github.com/izern/zf/test"
+	"github.com/izern/zf/types"
 	"github.com/izern/zf/util"
 	"github.com/stretchr/testify/assert"
 	"math"
@@ -130,3 +133,194 @@ func Test_SetValues(t *testing.T) {
 	}
 
 }
+
+func Test_GetDocument(t *testing.T) {
+	param := Before(t)
+
+	for _, handler := range handlers {
+		str, e := handler.Marshal(param)
+		assert.Nil(t, e, "marshal param failed.", param)
+		_, err := handler.SetValue(".port", "1234", str)
+		assert.Nil(t, err, ".port setValue error", handler, str)
+
+		doc, ok := handler.GetDocument().(map[string]interface{})
+		assert.True(t, ok, "document should be an object", handler)
+		portType, _ := types.GetType(doc["port"])
+		assert.Equal(t, types.ValueType(types.Number), portType, handler)
+
+		_, err = handler.Parse(`[1, 2]`)
+		assert.Nil(t, err, "parse array error", handler)
+		assert.Len(t, handler.GetDocument(), 2, handler)
+	}
+}
+
+func Test_ParseTypedValue(t *testing.T) {
+	v, err := ParseTypedValue("1.10", ValueString)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.10", v)
+
+	v, err = ParseTypedValue(" 123456789012345678901234567890\n", ValueInt)
+	assert.Nil(t, err)
+	assert.Equal(t, encjson.Number("123456789012345678901234567890"), v)
+
+	v, err = ParseTypedValue("+42", ValueInt)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), v)
+
+	v, err = ParseTypedValue("2", ValueFloat)
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, v)
+
+	v, err = ParseTypedValue("yes", ValueBool)
+	assert.NotNil(t, err)
+
+	v, err = ParseTypedValue("anything", ValueNull)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	v, err = ParseTypedValue(`{"a":[1]}`, ValueJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"a": []interface{}{encjson.Number("1")}}, v)
+
+	_, err = ParseTypedValue("1.5", ValueInt)
+	assert.NotNil(t, err)
+}
+
+func Test_SetParsed(t *testing.T) {
+	handler := NewHandler(&yaml2.YamlCodec{}, &yaml2.YamlCodec{}, "yaml")
+	result, err := handler.SetParsed(".version", "1.10", false, "version: 1\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "version: \"1.10\"\n", result)
+
+	result, err = handler.AppendParsed(".list", "", math.MaxInt16, "yes", "list: [a]\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "list:\n    - a\n    - \"yes\"\n", result)
+}
+
+func Test_SetCreate(t *testing.T) {
+	handler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
+	result, err := handler.SetParsed(".spec.template.metadata.labels.app", "web", true, "")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"spec":{"template":{"metadata":{"labels":{"app":"web"}}}}}`, result)
+
+	result, err = handler.SetParsed(".spec.containers[1].name", "web", true, `{"spec":{}}`)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"spec":{"containers":[null,{"name":"web"}]}}`, result)
+
+	_, err = handler.SetParsed(".a.b", 1, true, `{"a":"x"}`)
+	assert.NotNil(t, err)
+
+	_, err = handler.SetParsed(".a.b", 1, false, `{}`)
+	assert.NotNil(t, err)
+}
+
+func Test_NestedArrayPaths(t *testing.T) {
+	handler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
+	doc := `{"m":[[1,2],[3]],"g":[{"p":["a"]},{"p":["b"]}],"s":"x"}`
+
+	result, err := handler.AppendParsed(".m[1]", "", math.MaxInt16, 4, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"g":[{"p":["a"]},{"p":["b"]}],"m":[[1,2],[3,4]],"s":"x"}`, result)
+
+	result, err = handler.AppendParsed(".g[].p", "", 0, "z", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"g":[{"p":["z","a"]},{"p":["z","b"]}],"m":[[1,2],[3]],"s":"x"}`, result)
+
+	result, err = handler.SetParsed(".g[1].p[0]", "c", false, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"g":[{"p":["a"]},{"p":["c"]}],"m":[[1,2],[3]],"s":"x"}`, result)
+
+	result, err = handler.AppendParsed(".[0]", "", math.MaxInt16, 2, `[[1]]`)
+	assert.Nil(t, err)
+	assert.Equal(t, `[[1,2]]`, result)
+
+	for _, path := range []string{".s[0]", ".m[5]", ".s.a", ".x.y", ".m.a"} {
+		_, err = handler.AppendParsed(path, "", 0, 1, doc)
+		assert.NotNil(t, err, path)
+		_, err = handler.SetParsed(path, 1, false, doc)
+		assert.NotNil(t, err, path)
+	}
+}
+
+func Test_RenameMoveCopy(t *testing.T) {
+	handler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
+	doc := `{"a":{"b":1,"x":2},"l":[{"n":1},{"n":2}],"arr":[1,2,3]}`
+
+	result, err := handler.Rename(".l[].n", "name", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[1,2,3],"l":[{"name":1},{"name":2}]}`, result)
+
+	_, err = handler.Rename(".a.b", "x", doc)
+	assert.NotNil(t, err)
+	_, err = handler.Rename(".arr[0]", "x", doc)
+	assert.NotNil(t, err)
+
+	result, err = handler.Move(".a.b", ".c.d", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"x":2},"arr":[1,2,3],"c":{"d":1},"l":[{"n":1},{"n":2}]}`, result)
+
+	result, err = handler.Move(".arr[1]", ".z", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[1,3],"l":[{"n":1},{"n":2}],"z":2}`, result)
+
+	_, err = handler.Move(".a", ".a.q", doc)
+	assert.NotNil(t, err)
+
+	// 移动到原位置时不做修改
+	for _, path := range []string{".a", ".arr[1]"} {
+		result, err = handler.Move(path, path, doc)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[1,2,3],"l":[{"n":1},{"n":2}]}`, result)
+	}
+	_, err = handler.Move(".missing", ".missing", doc)
+	assert.NotNil(t, err)
+	// 不同写法的同一路径
+	result, err = handler.Move("$.a", ".a", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[1,2,3],"l":[{"n":1},{"n":2}]}`, result)
+	_, err = handler.Move("$.a", ".a.q", doc)
+	assert.NotNil(t, err)
+
+	// 同一数组内移动，目标下标按删除源值后的数组计算
+	result, err = handler.Move(".arr[0]", ".arr[1]", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[2,1,3],"l":[{"n":1},{"n":2}]}`, result)
+	result, err = handler.Move(".arr[0]", ".arr[2]", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[2,3,1],"l":[{"n":1},{"n":2}]}`, result)
+	result, err = handler.Move(".arr[2]", ".arr[0]", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[3,1,2],"l":[{"n":1},{"n":2}]}`, result)
+
+	result, err = handler.Copy(".l[0]", ".l[2]", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[1,2,3],"l":[{"n":1},{"n":2},{"n":1}]}`, result)
+
+	result, err = handler.Copy(".arr[0]", ".arr[1]", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":1,"x":2},"arr":[1,1,2,3],"l":[{"n":1},{"n":2}]}`, result)
+
+	_, err = handler.Copy(".l[]", ".q", doc)
+	assert.NotNil(t, err)
+}
+
+func Test_MarshalResult(t *testing.T) {
+	doc, e := handlers[1].GetValues(0, math.MaxUint32, ".", `{"a": null, "b": 1}`)
+	assert.Nil(t, e)
+	expected := map[string]string{"yaml": "null\n", "json": "null", "toml": "null"}
+	for _, expr := range []string{".missing", ".a", "null"} {
+		q, err := query.Compile(expr)
+		assert.Nil(t, err)
+		results, err := q.Run(doc)
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{nil}, results, expr)
+		for _, handler := range handlers {
+			res, err := MarshalResult(handler, results[0])
+			assert.Nil(t, err)
+			assert.Equal(t, expected[handler.GetCurrType()], res, expr)
+		}
+	}
+	res, err := MarshalResult(handlers[1], map[string]interface{}{"b": 1})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"b":1}`, res)
+}
@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// 数组合并策略
+const (
+	// ArrayReplace 使用overlay的数组替换base的数组
+	ArrayReplace = "replace"
+	// ArrayAppend 将overlay的元素追加到base的数组后
+	ArrayAppend = "append"
+	// ArrayUnique 追加overlay中base不存在的元素
+	ArrayUnique = "unique"
+	// ArrayMerge 按下标合并元素，写作 merge:<key> 时按元素中key的值合并object元素
+	ArrayMerge = "merge"
+)
+
+// overlay中值为null时的处理方式
+const (
+	// NullSet 将值设置为null
+	NullSet = "set"
+	// NullDelete 删除base中对应的键
+	NullDelete = "delete"
+	// NullIgnore 忽略，保留base中的值
+	NullIgnore = "ignore"
+)
+
+// MergeOptions 深度合并的选项
+type MergeOptions struct {
+	// Array 默认的数组合并策略
+	Array string
+	// Paths 按路径指定的数组合并策略，如 .proxies: merge:name，路径中的[]匹配数组的所有元素
+	Paths map[string]string
+	// Null overlay中值为null时的处理方式
+	Null string
+}
+
+// DefaultMergeOptions 数组整体替换，null覆盖原值
+func DefaultMergeOptions() MergeOptions {
+	return MergeOptions{
+		Array: ArrayReplace,
+		Null:  NullSet,
+	}
+}
+
+// Validate 校验合并策略
+func (o MergeOptions) Validate() types.ZfError {
+	if _, _, err := parseArrayStrategy(o.Array); err != nil {
+		return err
+	}
+	for path, strategy := range o.Paths {
+		if _, err := normalizeStrategyPath(path); err != nil {
+			return err
+		}
+		if _, _, err := parseArrayStrategy(strategy); err != nil {
+			return err
+		}
+	}
+	switch o.Null {
+	case NullSet, NullDelete, NullIgnore:
+		return nil
+	default:
+		return types.NewUnSupportError(fmt.Sprintf("null处理方式%s，可选值为set|delete|ignore", o.Null))
+	}
+}
+
+// normalizePaths 将Paths中不同写法的路径统一为合并时使用的写法，如 proxies、$.proxies 都转换为 .proxies
+func (o MergeOptions) normalizePaths() (MergeOptions, types.ZfError) {
+	if len(o.Paths) == 0 {
+		return o, nil
+	}
+	paths := make(map[string]string, len(o.Paths))
+	for path, strategy := range o.Paths {
+		normalized, err := normalizeStrategyPath(path)
+		if err != nil {
+			return o, err
+		}
+		paths[normalized] = strategy
+	}
+	o.Paths = paths
+	return o, nil
+}
+
+// normalizeStrategyPath 解析策略的路径并按合并时的写法重新生成，数组元素只能用[]匹配
+func normalizeStrategyPath(path string) (string, types.ZfError) {
+	paths, err := util.ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	result := "."
+	for _, p := range paths {
+		if p.Type == types.RootNode {
+			continue
+		}
+		if p.NodeKey != "" {
+			result = util.ChildPath(result, p.NodeKey)
+		}
+		switch p.Type {
+		case types.NormalNode:
+		case types.RangeNode:
+			if p.From != 0 || p.To != math.MaxInt16 {
+				return "", types.NewUnSupportError(fmt.Sprintf("合并策略的路径%s只能使用[]匹配数组的所有元素", path))
+			}
+			result = util.ElementPath(result)
+		default:
+			return "", types.NewUnSupportError(fmt.Sprintf("合并策略的路径%s只能使用[]匹配数组的所有元素", path))
+		}
+	}
+	return result, nil
+}
+
+// arrayStrategy 返回路径对应的数组合并策略
+func (o MergeOptions) arrayStrategy(path string) (string, string, types.ZfError) {
+	if strategy, ok := o.Paths[path]; ok {
+		return parseArrayStrategy(strategy)
+	}
+	return parseArrayStrategy(o.Array)
+}
+
+// parseArrayStrategy 解析数组合并策略，返回策略名和merge使用的键
+func parseArrayStrategy(strategy string) (string, string, types.ZfError) {
+	name, key := strategy, ""
+	if i := strings.Index(strategy, ":"); i >= 0 {
+		name, key = strategy[:i], strategy[i+1:]
+		if name != ArrayMerge || key == "" {
+			return "", "", types.NewUnSupportError(fmt.Sprintf("数组合并策略%s", strategy))
+		}
+	}
+	switch name {
+	case ArrayReplace, ArrayAppend, ArrayUnique, ArrayMerge:
+		return name, key, nil
+	default:
+		return "", "", types.NewUnSupportError(fmt.Sprintf("数组合并策略%s，可选值为replace|append|unique|merge|merge:<key>", strategy))
+	}
+}
+
+// Merge 将overlay深度合并到base中并返回合并结果，base会被修改
+// object按键递归合并，数组按策略合并，其他类型或类型不同时使用overlay的值
+func Merge(base interface{}, overlay interface{}, opts MergeOptions) (interface{}, types.ZfError) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts, err := opts.normalizePaths()
+	if err != nil {
+		return nil, err
+	}
+	if overlay == nil && opts.Null != NullSet {
+		return base, nil
+	}
+	return mergeValue(base, overlay, ".", opts)
+}
+
+func mergeValue(base interface{}, overlay interface{}, path string, opts MergeOptions) (interface{}, types.ZfError) {
+	switch o := overlay.(type) {
+	case map[string]interface{}:
+		b, ok := base.(map[string]interface{})
+		if !ok {
+			return opts.dropNulls(overlay), nil
+		}
+		for k, v := range o {
+			if v == nil {
+				switch opts.Null {
+				case NullDelete:
+					delete(b, k)
+				case NullSet:
+					b[k] = nil
+				}
+				continue
+			}
+			old, exists := b[k]
+			if !exists {
+				b[k] = opts.dropNulls(v)
+				continue
+			}
+			merged, err := mergeValue(old, v, util.ChildPath(path, k), opts)
+			if err != nil {
+				return nil, err
+			}
+			b[k] = merged
+		}
+		return b, nil
+	case []interface{}:
+		b, ok := base.([]interface{})
+		if !ok {
+			return opts.dropNulls(overlay), nil
+		}
+		return mergeArray(b, o, path, opts)
+	default:
+		return overlay, nil
+	}
+}
+
+func mergeArray(base []interface{}, overlay []interface{}, path string, opts MergeOptions) (interface{}, types.ZfError) {
+	strategy, key, err := opts.arrayStrategy(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strategy {
+	case ArrayAppend:
+		return append(base, opts.dropNulls(overlay).([]interface{})...), nil
+	case ArrayUnique:
+		// base保持不变，只追加base和已追加的元素中不存在的overlay元素
+		seen := make(map[string]bool, len(base)+len(overlay))
+		for _, item := range base {
+			id, err := json.Canonicalize(item)
+			if err != nil {
+				return nil, err
+			}
+			seen[string(id)] = true
+		}
+		for _, item := range opts.dropNulls(overlay).([]interface{}) {
+			id, err := json.Canonicalize(item)
+			if err != nil {
+				return nil, err
+			}
+			if seen[string(id)] {
+				continue
+			}
+			seen[string(id)] = true
+			base = append(base, item)
+		}
+		return base, nil
+	case ArrayMerge:
+		if key == "" {
+			for i, item := range overlay {
+				if i >= len(base) {
+					base = append(base, opts.dropNulls(item))
+					continue
+				}
+				merged, err := mergeValue(base[i], item, util.ElementPath(path), opts)
+				if err != nil {
+					return nil, err
+				}
+				base[i] = merged
+			}
+			return base, nil
+		}
+		return mergeArrayByKey(base, overlay, key, path, opts)
+	default:
+		return opts.dropNulls(overlay), nil
+	}
+}
+
+// mergeArrayByKey 按key的值合并object元素，找不到对应元素或不是object的元素追加到末尾
+func mergeArrayByKey(base []interface{}, overlay []interface{}, key string, path string, opts MergeOptions) (interface{}, types.ZfError) {
+	index := make(map[string]int, len(base))
+	for i, item := range base {
+		if id, ok, err := elementKey(item, key); err != nil {
+			return nil, err
+		} else if ok {
+			if _, exists := index[id]; !exists {
+				index[id] = i
+			}
+		}
+	}
+	for _, item := range overlay {
+		id, ok, err := elementKey(item, key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			base = append(base, opts.dropNulls(item))
+			continue
+		}
+		if i, exists := index[id]; exists {
+			merged, err := mergeValue(base[i], item, util.ElementPath(path), opts)
+			if err != nil {
+				return nil, err
+			}
+			base[i] = merged
+			continue
+		}
+		index[id] = len(base)
+		base = append(base, opts.dropNulls(item))
+	}
+	return base, nil
+}
+
+// dropNulls 处理直接使用的overlay中的值，null处理方式为delete或ignore时递归删除object中值为null的键
+// 这些键在base中不存在，删除和忽略的结果相同
+func (o MergeOptions) dropNulls(v interface{}) interface{} {
+	if o.Null == NullSet {
+		return v
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if item != nil {
+				res[k] = o.dropNulls(item)
+			}
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = o.dropNulls(item)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// elementKey 返回object元素中key的值的规范化json，用于比较
+func elementKey(item interface{}, key string) (string, bool, types.ZfError) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return "", false, nil
+	}
+	v, ok := m[key]
+	if !ok {
+		return "", false, nil
+	}
+	id, err := json.Canonicalize(v)
+	if err != nil {
+		return "", false, err
+	}
+	return string(id), true, nil
+}
+
+// MergePatch 按RFC 7396应用JSON Merge Patch，返回修改后的文档，原文档不会被修改
+// patch中的null删除对应的键，object递归合并，其他值(包括数组)直接替换
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	return mergePatch(util.DeepCopy(target), patch)
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return util.DeepCopy(patch)
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{}, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// DiffMergePatch 生成从a修改为b的RFC 7396 JSON Merge Patch
+// merge patch中的null表示删除，b中与a不同的null值无法表示，此时返回错误
+func DiffMergePatch(a interface{}, b interface{}) (interface{}, types.ZfError) {
+	return diffMergePatch(a, b, ".")
+}
+
+func diffMergePatch(a interface{}, b interface{}, path string) (interface{}, types.ZfError) {
+	av, aIsObject := a.(map[string]interface{})
+	bv, bIsObject := b.(map[string]interface{})
+	if !aIsObject || !bIsObject {
+		if err := checkMergePatchValue(b, path); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	patch := make(map[string]interface{})
+	for k := range av {
+		if _, ok := bv[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, bItem := range bv {
+		aItem, ok := av[k]
+		if ok {
+			equal, err := Equal(aItem, bItem)
+			if err != nil {
+				return nil, err
+			}
+			if equal {
+				continue
+			}
+		}
+		if !ok {
+			// 新增的键相当于与空object比较
+			aItem = map[string]interface{}{}
+		}
+		item, err := diffMergePatch(aItem, bItem, util.ChildPath(path, k))
+		if err != nil {
+			return nil, err
+		}
+		patch[k] = item
+	}
+	return patch, nil
+}
+
+// checkMergePatchValue 替换的值中不能包含object中的null，null会被当作删除
+func checkMergePatchValue(v interface{}, path string) types.ZfError {
+	switch val := v.(type) {
+	case nil:
+		return types.NewUnSupportError(fmt.Sprintf("merge patch无法将%s设置为null", path))
+	case map[string]interface{}:
+		for k, item := range val {
+			if err := checkMergePatchValue(item, util.ChildPath(path, k)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"github.com/izern/zf/codec/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+
+}
+
+func mergeJSON(t *testing.T, base, overlay string, opts MergeOptions) string {
+	codec := &json.JSONCodec{}
+	b, err := codec.Unmarshal([]byte(base))
+	assert.Nil(t, err)
+	o, err := codec.Unmarshal([]byte(overlay))
+	assert.Nil(t, err)
+	res, err := Merge(b, o, opts)
+	assert.Nil(t, err)
+	out, err := codec.Marshal(res)
+	assert.Nil(t, err)
+	return string(out)
+}
+
+func Test_Merge(t *testing.T) {
+	base := `{"a":{"b":1,"c":2},"l":[1,2],"p":[{"name":"x","port":1},{"name":"y","port":2}],"n":1}`
+	overlay := `{"a":{"c":3,"d":4},"l":[2,3],"p":[{"name":"y","port":20},{"name":"z"}],"n":null}`
+
+	opts := DefaultMergeOptions()
+	assert.Equal(t, `{"a":{"b":1,"c":3,"d":4},"l":[2,3],"n":null,"p":[{"name":"y","port":20},{"name":"z"}]}`,
+		mergeJSON(t, base, overlay, opts))
+
+	opts.Array = ArrayAppend
+	opts.Null = NullDelete
+	assert.Equal(t, `{"a":{"b":1,"c":3,"d":4},"l":[1,2,2,3],"p":[{"name":"x","port":1},{"name":"y","port":2},{"name":"y","port":20},{"name":"z"}]}`,
+		mergeJSON(t, base, overlay, opts))
+
+	opts.Array = ArrayUnique
+	opts.Null = NullIgnore
+	opts.Paths = map[string]string{".p": "merge:name"}
+	assert.Equal(t, `{"a":{"b":1,"c":3,"d":4},"l":[1,2,3],"n":1,"p":[{"name":"x","port":1},{"name":"y","port":20},{"name":"z"}]}`,
+		mergeJSON(t, base, overlay, opts))
+
+	opts.Paths = map[string]string{".p": ArrayMerge, ".p[].tags": ArrayAppend}
+	assert.Equal(t, `{"p":[{"name":"y","tags":[1,2]}]}`,
+		mergeJSON(t, `{"p":[{"name":"x","tags":[1]}]}`, `{"p":[{"name":"y","tags":[2]}]}`, opts))
+
+	// 不同写法的路径
+	for _, path := range []string{"p", "$.p", ".p"} {
+		opts.Paths = map[string]string{path: ArrayAppend}
+		assert.Equal(t, `{"p":[1,1]}`, mergeJSON(t, `{"p":[1]}`, `{"p":[1]}`, opts), path)
+	}
+	opts.Array = ArrayMerge
+	opts.Paths = map[string]string{"$.a\\.b[].c": ArrayAppend}
+	assert.Equal(t, `{"a.b":[{"c":[1,2]}]}`, mergeJSON(t, `{"a.b":[{"c":[1]}]}`, `{"a.b":[{"c":[2]}]}`, opts))
+	opts.Array = ArrayUnique
+	for _, path := range []string{".p[0]", ".p[1,2]", ".."} {
+		opts.Paths = map[string]string{path: ArrayAppend}
+		_, err := Merge(nil, nil, opts)
+		assert.NotNil(t, err, path)
+	}
+	opts.Paths = nil
+
+	// unique只追加base中不存在的元素，base中已有的重复元素保留
+	assert.Equal(t, `{"l":[1,1,2,3]}`, mergeJSON(t, `{"l":[1,1,2]}`, `{"l":[2,3,3,1]}`, opts))
+
+	// overlay中新增的值也删除值为null的键
+	for _, null := range []string{NullDelete, NullIgnore} {
+		opts = DefaultMergeOptions()
+		opts.Null = null
+		assert.Equal(t, `{"a":1,"b":{"d":2,"e":[{"g":3}]}}`,
+			mergeJSON(t, `{"a":1}`, `{"b":{"c":null,"d":2,"e":[{"f":null,"g":3}]}}`, opts))
+		assert.Equal(t, `{"a":{"d":2}}`, mergeJSON(t, `{"a":1}`, `{"a":{"c":null,"d":2}}`, opts))
+		opts.Array = ArrayAppend
+		assert.Equal(t, `{"l":[1,{"g":3}]}`, mergeJSON(t, `{"l":[1]}`, `{"l":[{"f":null,"g":3}]}`, opts))
+	}
+	opts.Null = NullSet
+	assert.Equal(t, `{"a":1,"b":{"c":null}}`, mergeJSON(t, `{"a":1}`, `{"b":{"c":null}}`, opts))
+
+	opts.Array = "merge:"
+	_, err := Merge(nil, nil, opts)
+	assert.NotNil(t, err)
+	opts.Array = ArrayReplace
+	opts.Null = "drop"
+	_, err = Merge(nil, nil, opts)
+	assert.NotNil(t, err)
+}
+
+func Test_MergePatch(t *testing.T) {
+	// RFC 7396 附录A中的示例
+	cases := []struct {
+		target, patch, expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	codec := &json.JSONCodec{}
+	for _, c := range cases {
+		target := unmarshalJSON(t, c.target)
+		res := MergePatch(target, unmarshalJSON(t, c.patch))
+		out, err := codec.Marshal(res)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, string(out), c.patch)
+		// 原文档不变
+		out, _ = codec.Marshal(target)
+		assert.Equal(t, c.target, string(out))
+	}
+}
+
+func Test_DiffMergePatch(t *testing.T) {
+	a := unmarshalJSON(t, `{"a":{"b":1,"c":2},"d":[1,2],"e":"x","f":1.0}`)
+	b := unmarshalJSON(t, `{"a":{"b":1,"c":3,"n":{"x":1}},"d":[1],"f":1,"g":true}`)
+	patch, err := DiffMergePatch(a, b)
+	assert.Nil(t, err)
+	out, _ := (&json.JSONCodec{}).Marshal(patch)
+	assert.Equal(t, `{"a":{"c":3,"n":{"x":1}},"d":[1],"e":null,"g":true}`, string(out))
+	equal, err := Equal(MergePatch(a, patch), b)
+	assert.Nil(t, err)
+	assert.True(t, equal)
+
+	_, err = DiffMergePatch(a, unmarshalJSON(t, `{"a":{"b":null}}`))
+	assert.NotNil(t, err)
+}
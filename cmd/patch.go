@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// patchOp RFC 6902 JSON Patch中的一个操作
+type patchOp struct {
+	Op       string
+	Path     string
+	From     string
+	Value    interface{}
+	HasValue bool
+}
+
+// ApplyPatch 按RFC 6902依次执行patch中的操作，返回修改后的文档
+// 所有操作都在副本上执行，任何一个操作失败时返回错误，原文档不会被修改
+func ApplyPatch(doc interface{}, patch interface{}) (interface{}, types.ZfError) {
+	ops, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	result := util.DeepCopy(doc)
+	for i, op := range ops {
+		var cause error
+		result, cause = applyOp(result, op)
+		if cause != nil {
+			return nil, types.NewPatchError(i, op.Op, op.Path, cause.Error())
+		}
+	}
+	return result, nil
+}
+
+// parsePatch 校验并解析patch文档，patch必须是操作的数组
+func parsePatch(patch interface{}) ([]patchOp, types.ZfError) {
+	items, ok := patch.([]interface{})
+	if !ok {
+		patchType, _ := types.GetType(patch)
+		return nil, types.NewUnSupportError(fmt.Sprintf("patch应为array，实际为%s", patchType))
+	}
+	ops := make([]patchOp, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, types.NewPatchError(i, "", "", "操作应为object")
+		}
+		op := patchOp{}
+		op.Op, _ = m["op"].(string)
+		path, ok := m["path"].(string)
+		if !ok {
+			return nil, types.NewPatchError(i, op.Op, "", "缺少path")
+		}
+		op.Path = path
+		op.Value, op.HasValue = m["value"]
+		switch op.Op {
+		case "add", "replace", "test":
+			if !op.HasValue {
+				return nil, types.NewPatchError(i, op.Op, op.Path, "缺少value")
+			}
+		case "move", "copy":
+			if op.From, ok = m["from"].(string); !ok {
+				return nil, types.NewPatchError(i, op.Op, op.Path, "缺少from")
+			}
+		case "remove":
+		default:
+			return nil, types.NewPatchError(i, op.Op, op.Path, "不支持的操作，可选值为add|remove|replace|move|copy|test")
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+func applyOp(doc interface{}, op patchOp) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add":
+		return pointerAdd(doc, path, util.DeepCopy(op.Value))
+	case "remove":
+		return pointerRemove(doc, path)
+	case "replace":
+		if _, err = pointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		if doc, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, util.DeepCopy(op.Value))
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return pointerAdd(doc, path, util.DeepCopy(v))
+		}
+		if op.From == op.Path {
+			return doc, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("不能移动到自身的子路径%s", op.From)
+		}
+		if doc, err = pointerRemove(doc, from); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	default:
+		v, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		equal, zfError := Equal(v, op.Value)
+		if zfError != nil {
+			return nil, zfError.Error()
+		}
+		if !equal {
+			return nil, fmt.Errorf("值为%s，期望%s", diffValueString(v), diffValueString(op.Value))
+		}
+		return doc, nil
+	}
+}
+
+// parsePointer 解析RFC 6901 JSON Pointer，空字符串表示整个文档
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON Pointer应以/开头: %s", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// formatPointer 将键列表转换为JSON Pointer
+func formatPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// arrayIndex 解析数组下标，max为允许的最大值
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("数组下标格式错误: %s", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("数组下标格式错误: %s", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("数组下标%d越界，最大:%d", index, max)
+	}
+	return index, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for i, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("找不到键:%s", formatPointer(path[:i+1]))
+			}
+			doc = v
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("%s不是object或array", formatPointer(path[:i]))
+		}
+	}
+	return doc, nil
+}
+
+// pointerAdd 在路径处添加值，object设置键，array在下标处插入，- 表示追加到末尾
+func pointerAdd(doc interface{}, path []string, v interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			node[token] = v
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("找不到键:%s", token)
+		}
+		child, err := pointerAdd(child, path[1:], v)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []interface{}:
+		if len(path) == 1 {
+			index := len(node)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			result := make([]interface{}, 0, len(node)+1)
+			result = append(result, node[:index]...)
+			result = append(result, v)
+			return append(result, node[index:]...), nil
+		}
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if node[index], err = pointerAdd(node[index], path[1:], v); err != nil {
+			return nil, err
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("父节点不是object或array")
+	}
+}
+
+// pointerRemove 删除路径处的值，路径必须存在
+func pointerRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("找不到键:%s", token)
+		}
+		if len(path) == 1 {
+			delete(node, token)
+			return node, nil
+		}
+		child, err := pointerRemove(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 1 {
+			result := make([]interface{}, 0, len(node)-1)
+			result = append(result, node[:index]...)
+			return append(result, node[index+1:]...), nil
+		}
+		if node[index], err = pointerRemove(node[index], path[1:]); err != nil {
+			return nil, err
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("父节点不是object或array")
+	}
+}
+
+// DiffPatch 生成从a修改为b的RFC 6902 JSON Patch，object按键排序比较，数组按下标比较
+func DiffPatch(a interface{}, b interface{}) ([]interface{}, types.ZfError) {
+	ops := make([]interface{}, 0)
+	err := diffPatch(a, b, nil, &ops)
+	return ops, err
+}
+
+func diffPatch(a interface{}, b interface{}, path []string, ops *[]interface{}) types.ZfError {
+	child := func(token string) []string {
+		return append(append(make([]string, 0, len(path)+1), path...), token)
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			aItem, inA := av[k]
+			bItem, inB := bv[k]
+			switch {
+			case !inB:
+				*ops = append(*ops, patchValue("remove", child(k), nil, false))
+			case !inA:
+				*ops = append(*ops, patchValue("add", child(k), bItem, true))
+			default:
+				if err := diffPatch(aItem, bItem, child(k), ops); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		common := util.Min(len(av), len(bv))
+		for i := 0; i < common; i++ {
+			if err := diffPatch(av[i], bv[i], child(strconv.Itoa(i)), ops); err != nil {
+				return err
+			}
+		}
+		// 从后往前删除，避免下标变化
+		for i := len(av) - 1; i >= common; i-- {
+			*ops = append(*ops, patchValue("remove", child(strconv.Itoa(i)), nil, false))
+		}
+		for i := common; i < len(bv); i++ {
+			*ops = append(*ops, patchValue("add", child(strconv.Itoa(i)), bv[i], true))
+		}
+		return nil
+	}
+	equal, err := Equal(a, b)
+	if err != nil {
+		return err
+	}
+	if !equal {
+		*ops = append(*ops, patchValue("replace", path, b, true))
+	}
+	return nil
+}
+
+func patchValue(op string, path []string, v interface{}, hasValue bool) map[string]interface{} {
+	res := map[string]interface{}{
+		"op":   op,
+		"path": formatPointer(path),
+	}
+	if hasValue {
+		res["value"] = v
+	}
+	return res
+}
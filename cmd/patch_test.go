@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/izern/zf/codec/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+
+}
+
+func unmarshalJSON(t *testing.T, text string) interface{} {
+	v, err := (&json.JSONCodec{}).Unmarshal([]byte(text))
+	assert.Nil(t, err)
+	return v
+}
+
+func Test_ApplyPatch(t *testing.T) {
+	// RFC 6902 附录A中的示例
+	cases := []struct {
+		doc, patch, expected string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"a":1}`, `[{"op":"copy","from":"/a","path":"/b"},{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+	for _, c := range cases {
+		res, err := ApplyPatch(unmarshalJSON(t, c.doc), unmarshalJSON(t, c.patch))
+		assert.Nil(t, err, c.patch)
+		out, _ := (&json.JSONCodec{}).Marshal(res)
+		assert.Equal(t, c.expected, string(out), c.patch)
+	}
+
+	errors := []struct {
+		patch, message string
+	}{
+		{`[{"op":"add","path":"/a","value":1},{"op":"test","path":"/baz","value":"bar"}]`, `patch[1] (test /baz)执行失败: 值为"qux"，期望"bar"`},
+		{`[{"op":"remove","path":"/missing"}]`, `patch[0] (remove /missing)执行失败: 找不到键:missing`},
+		{`[{"op":"add","path":"/foo/5","value":1}]`, `patch[0] (add /foo/5)执行失败: 数组下标5越界，最大:1`},
+		{`[{"op":"add","path":"/foo/01","value":1}]`, `patch[0] (add /foo/01)执行失败: 数组下标格式错误: 01`},
+		{`[{"op":"move","from":"/foo","path":"/foo/0"}]`, `patch[0] (move /foo/0)执行失败: 不能移动到自身的子路径/foo`},
+		{`[{"op":"add","path":"/a"}]`, `patch[0] (add /a)执行失败: 缺少value`},
+		{`[{"op":"rename","path":"/a"}]`, `patch[0] (rename /a)执行失败: 不支持的操作，可选值为add|remove|replace|move|copy|test`},
+	}
+	for _, e := range errors {
+		doc := unmarshalJSON(t, `{"baz":"qux","foo":["bar"]}`)
+		_, err := ApplyPatch(doc, unmarshalJSON(t, e.patch))
+		assert.NotNil(t, err)
+		assert.Equal(t, e.message, err.Error().Error())
+		// 失败时原文档不变
+		assert.Equal(t, map[string]interface{}{"baz": "qux", "foo": []interface{}{"bar"}}, doc)
+	}
+}
+
+func Test_DiffPatch(t *testing.T) {
+	a := unmarshalJSON(t, `{"a":{"b":1,"c":[1,2,3,4]},"d":"x","e":[1]}`)
+	b := unmarshalJSON(t, `{"a":{"b":2,"c":[1,5]},"e":{"x":1},"f/g":null}`)
+	ops, err := DiffPatch(a, b)
+	assert.Nil(t, err)
+	out, _ := (&json.JSONCodec{}).Marshal(ops)
+	fmt.Println(string(out))
+	assert.Equal(t, `[{"op":"replace","path":"/a/b","value":2},{"op":"replace","path":"/a/c/1","value":5},`+
+		`{"op":"remove","path":"/a/c/3"},{"op":"remove","path":"/a/c/2"},{"op":"remove","path":"/d"},`+
+		`{"op":"replace","path":"/e","value":{"x":1}},{"op":"add","path":"/f~1g","value":null}]`, string(out))
+
+	res, err := ApplyPatch(a, ops)
+	assert.Nil(t, err)
+	equal, err := Equal(res, b)
+	assert.Nil(t, err)
+	assert.True(t, equal)
+}
@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"github.com/izern/zf/codec/cbor"
+	"github.com/izern/zf/codec/hocon"
 	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/codec/msgpack"
+	"github.com/izern/zf/codec/plist"
 	"github.com/izern/zf/codec/toml"
 	yaml2 "github.com/izern/zf/codec/yaml"
 )
@@ -11,9 +15,19 @@ func init() {
 	yamlHandler := NewHandler(&yaml2.YamlCodec{}, &yaml2.YamlCodec{}, "yaml")
 	jsonHandler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
 	tomlHandler := NewHandler(&toml.TomlCodec{}, &toml.TomlCodec{}, "toml")
+	msgpackHandler := NewHandler(&msgpack.MsgpackCodec{}, &msgpack.MsgpackCodec{}, "msgpack")
+	cborHandler := NewHandler(&cbor.CborCodec{}, &cbor.CborCodec{}, "cbor")
+	plistHandler := NewHandler(&plist.PlistCodec{}, &plist.PlistCodec{}, "plist")
+	bplistHandler := NewHandler(&plist.PlistCodec{Binary: true}, &plist.PlistCodec{Binary: true}, "bplist")
+	hoconHandler := NewHandler(&hocon.HoconCodec{}, &hocon.HoconCodec{}, "hocon")
 
 	Regist(yamlHandler)
 	Regist(jsonHandler)
 	Regist(tomlHandler)
+	Regist(msgpackHandler)
+	Regist(cborHandler)
+	Regist(plistHandler)
+	Regist(bplistHandler)
+	Regist(hoconHandler)
 
 }
@@ -3,7 +3,11 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"github.com/izern/zf/codec"
 	"github.com/izern/zf/types"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 var extCommandMap = make(map[string]types.TypeCommand)
@@ -30,3 +34,42 @@ func GetAllCmd() []types.TypeCommand {
 	}
 	return result
 }
+
+// SetMarshalOptions 设置所有已注册格式输出时使用的格式选项
+func SetMarshalOptions(opts codec.MarshalOptions) {
+	for _, v := range extCommandMap {
+		if handler, ok := v.(*Handler); ok {
+			handler.Options = opts
+		}
+	}
+}
+
+// GetCmdByFile 根据文件扩展名返回对应格式，多个格式使用同一扩展名时按名称取第一个
+func GetCmdByFile(file string) (types.TypeCommand, error) {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == "" {
+		return nil, errors.New(fmt.Sprintf("无法根据扩展名识别%s的格式，请指定格式", file))
+	}
+	var names []string
+	for name, v := range extCommandMap {
+		handler, ok := v.(*Handler)
+		if !ok {
+			continue
+		}
+		c, ok := handler.Marshaler.(codec.Codec)
+		if !ok {
+			continue
+		}
+		for _, e := range c.GetInfo().FileExtensions {
+			if e == ext {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New(fmt.Sprintf("无法根据扩展名识别%s的格式，请指定格式", file))
+	}
+	sort.Strings(names)
+	return extCommandMap[names[0]], nil
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/izern/zf/types"
+)
+
+func init() {
+
+}
+
+// Sort 对指定路径的数组排序，路径为object时按键排序，RecursiveKeys 时包括所有层级的object
+func (receiver *Handler) Sort(path string, opts types.SortOptions, text string) (string, types.ZfError) {
+	paths, err := receiver.validatePathAndParse(path, text)
+	if err != nil {
+		return "", err
+	}
+	targets, err := receiver.resolveTargets(paths[1:], false)
+	if err != nil {
+		return "", err
+	}
+	for _, target := range targets {
+		switch v := target.get().(type) {
+		case []interface{}:
+			sorted, err := SortArray(v, opts)
+			if err != nil {
+				return "", err
+			}
+			target.set(sorted)
+		case map[string]interface{}:
+			target.set(SortKeys(v, opts.RecursiveKeys))
+		default:
+			valueType, _ := types.GetType(v)
+			return "", types.NewUnSupportError(fmt.Sprintf("sort只支持array和object，%s的类型为%s", path, valueType))
+		}
+	}
+	return receiver.PrintToString()
+}
+
+// SortKeys 按键的字典序重建object，recursive为true时包括值中所有层级的object
+// object没有顺序，重建后由输出时的 SortKeys 选项保证按键排序
+func SortKeys(obj map[string]interface{}, recursive bool) map[string]interface{} {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make(map[string]interface{}, len(obj))
+	for _, k := range keys {
+		v := obj[k]
+		if recursive {
+			v = sortNestedKeys(v)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// sortNestedKeys 对值中所有层级的object按键排序，包括数组中的object
+func sortNestedKeys(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return SortKeys(val, true)
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for i, item := range val {
+			result[i] = sortNestedKeys(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// Uniq 删除指定路径数组中的重复元素，保留第一次出现的元素
+func (receiver *Handler) Uniq(path string, by string, text string) (string, types.ZfError) {
+	paths, err := receiver.validatePathAndParse(path, text)
+	if err != nil {
+		return "", err
+	}
+	targets, err := receiver.resolveTargets(paths[1:], false)
+	if err != nil {
+		return "", err
+	}
+	for _, target := range targets {
+		array, ok := target.get().([]interface{})
+		if !ok {
+			valueType, _ := types.GetType(target.get())
+			return "", types.NewUnSupportError(fmt.Sprintf("uniq只支持array，%s的类型为%s", path, valueType))
+		}
+		unique, err := UniqArray(array, by)
+		if err != nil {
+			return "", err
+		}
+		target.set(unique)
+	}
+	return receiver.PrintToString()
+}
+
+// SortArray 稳定排序，返回新的数组
+// 不同类型按 null < bool < number < string < array < object 排列，数字按数值比较
+func SortArray(array []interface{}, opts types.SortOptions) ([]interface{}, types.ZfError) {
+	keys := make([]interface{}, len(array))
+	for i, item := range array {
+		keys[i] = sortKey(item, opts.By)
+	}
+	order := make([]int, len(array))
+	for i := range order {
+		order[i] = i
+	}
+	var err types.ZfError
+	sort.SliceStable(order, func(i, j int) bool {
+		c, e := compareValues(keys[order[i]], keys[order[j]])
+		if e != nil && err == nil {
+			err = e
+		}
+		if opts.Reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]interface{}, len(array))
+	for i, index := range order {
+		res[i] = array[index]
+	}
+	return res, nil
+}
+
+// UniqArray 按第一次出现的顺序保留不同的元素，by不为空时按元素中该路径的值判断，数字按数值比较
+func UniqArray(array []interface{}, by string) ([]interface{}, types.ZfError) {
+	res := make([]interface{}, 0, len(array))
+	seen := map[string]bool{}
+	for _, item := range array {
+		key, err := valueKey(sortKey(item, by))
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// sortKey by为空或 . 时返回元素本身，否则返回元素中该路径的值，不存在时为nil
+func sortKey(item interface{}, by string) interface{} {
+	if by == "" || by == "." {
+		return item
+	}
+	return lookupKey(item, by)
+}
+
+// typeRank 不同类型之间的顺序
+var typeRank = map[types.ValueType]int{
+	types.Null:   0,
+	types.Bool:   1,
+	types.Number: 2,
+	types.String: 3,
+	types.Array:  4,
+	types.Object: 5,
+}
+
+// compareValues 比较两个值，数组按元素依次比较，object按规范化的json比较
+func compareValues(a interface{}, b interface{}) (int, types.ZfError) {
+	aType, err := types.GetType(a)
+	if err != nil {
+		return 0, err
+	}
+	bType, err := types.GetType(b)
+	if err != nil {
+		return 0, err
+	}
+	if aType != bType {
+		return compareInt(typeRank[aType], typeRank[bType]), nil
+	}
+	switch aType {
+	case types.Bool:
+		x, y := a.(bool), b.(bool)
+		if x == y {
+			return 0, nil
+		}
+		if !x {
+			return -1, nil
+		}
+		return 1, nil
+	case types.Number:
+		return compareNumber(a, b), nil
+	case types.String:
+		return strings.Compare(a.(string), b.(string)), nil
+	case types.Array:
+		x, y := StatsValues(a), StatsValues(b)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c, err := compareValues(x[i], y[i]); err != nil || c != 0 {
+				return c, err
+			}
+		}
+		return compareInt(len(x), len(y)), nil
+	case types.Object:
+		x, err := valueKey(a)
+		if err != nil {
+			return 0, err
+		}
+		y, err := valueKey(b)
+		if err != nil {
+			return 0, err
+		}
+		return strings.Compare(x, y), nil
+	default:
+		return 0, nil
+	}
+}
+
+func compareInt(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	encjson "encoding/json"
+	"fmt"
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_Sort(t *testing.T) {
+	handler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
+	doc := `{"rules":["b","a","c","a"],"groups":[{"name":"z","l":[3,1,2]},{"name":"a","l":[2,"x",null,true,1.5]},{"name":"m","l":[]}]}`
+
+	result, err := handler.Sort(".rules", types.SortOptions{}, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"groups":[{"l":[3,1,2],"name":"z"},{"l":[2,"x",null,true,1.5],"name":"a"},{"l":[],"name":"m"}],"rules":["a","a","b","c"]}`, result)
+
+	result, err = handler.Sort(".groups[].l", types.SortOptions{Reverse: true}, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"groups":[{"l":[3,2,1],"name":"z"},{"l":["x",2,1.5,true,null],"name":"a"},{"l":[],"name":"m"}],"rules":["b","a","c","a"]}`, result)
+
+	result, err = handler.Sort(".groups", types.SortOptions{By: ".name"}, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"groups":[{"l":[2,"x",null,true,1.5],"name":"a"},{"l":[],"name":"m"},{"l":[3,1,2],"name":"z"}],"rules":["b","a","c","a"]}`, result)
+
+	result, err = handler.Sort(".", types.SortOptions{RecursiveKeys: true}, `{"z":{"b":1,"a":[{"y":1,"x":2}]},"a":0}`)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":0,"z":{"a":[{"x":2,"y":1}],"b":1}}`, result)
+
+	_, err = handler.Sort(".groups[0].name", types.SortOptions{}, doc)
+	assert.Equal(t, "不支持的操作：sort只支持array和object，.groups[0].name的类型为string", err.Error().Error())
+	_, err = handler.Sort(".missing", types.SortOptions{}, doc)
+	assert.NotNil(t, err)
+}
+
+func Test_SortKeys(t *testing.T) {
+	nested := map[string]interface{}{"b": 1, "a": 2}
+	array := []interface{}{map[string]interface{}{"d": nested}}
+	obj := map[string]interface{}{"y": nested, "x": array}
+
+	sorted := SortKeys(obj, false)
+	assert.Equal(t, obj, sorted)
+	// 不递归时只重建顶层object
+	assert.Equal(t, fmt.Sprintf("%p", nested), fmt.Sprintf("%p", sorted["y"]))
+
+	sorted = SortKeys(obj, true)
+	assert.Equal(t, obj, sorted)
+	assert.NotEqual(t, fmt.Sprintf("%p", nested), fmt.Sprintf("%p", sorted["y"]))
+	assert.NotEqual(t, fmt.Sprintf("%p", array), fmt.Sprintf("%p", sorted["x"]))
+}
+
+func Test_SortArray(t *testing.T) {
+	// 相同的元素保持原来的顺序，倒序时也一样
+	array := []interface{}{
+		map[string]interface{}{"n": 2, "id": "a"},
+		map[string]interface{}{"n": encjson.Number("1"), "id": "b"},
+		map[string]interface{}{"id": "c"},
+		map[string]interface{}{"n": 2.0, "id": "d"},
+	}
+	sorted, err := SortArray(array, types.SortOptions{By: "n"})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{array[2], array[1], array[0], array[3]}, sorted)
+	sorted, err = SortArray(array, types.SortOptions{By: ".n", Reverse: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{array[0], array[3], array[1], array[2]}, sorted)
+
+	sorted, err = SortArray([]interface{}{[]interface{}{1, 2}, []interface{}{1}, map[string]interface{}{}, "b", false}, types.SortOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{false, "b", []interface{}{1}, []interface{}{1, 2}, map[string]interface{}{}}, sorted)
+}
+
+func Test_Uniq(t *testing.T) {
+	handler := NewHandler(&json.JSONCodec{}, &json.JSONCodec{}, "json")
+	doc := `{"rules":["b","a","b",1,1.0,{"x":1},{"x":1.0}],"proxies":[{"name":"a","p":1},{"name":"b"},{"name":"a","p":2},{"p":3}]}`
+
+	result, err := handler.Uniq(".rules", "", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"proxies":[{"name":"a","p":1},{"name":"b"},{"name":"a","p":2},{"p":3}],"rules":["b","a",1,{"x":1}]}`, result)
+
+	result, err = handler.Uniq(".proxies", ".name", doc)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"proxies":[{"name":"a","p":1},{"name":"b"},{"p":3}],"rules":["b","a","b",1,1.0,{"x":1},{"x":1.0}]}`, result)
+
+	_, err = handler.Uniq(".proxies[0]", "", doc)
+	assert.Equal(t, "不支持的操作：uniq只支持array，.proxies[0]的类型为object", err.Error().Error())
+}
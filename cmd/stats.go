@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	encjson "encoding/json"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/types"
+)
+
+func init() {
+
+}
+
+// Stats 一组值的统计结果，数字相关的字段只统计其中的数字
+type Stats struct {
+	// Count 值的个数
+	Count int
+	// Types 每种类型的个数
+	Types map[types.ValueType]int
+	// Distinct 不同值的个数，数字按数值比较
+	Distinct int
+	// Sum Avg Min Max 没有数字时为nil
+	Sum interface{}
+	Avg interface{}
+	Min interface{}
+	Max interface{}
+}
+
+// ToValue 转换为可以按任意格式输出的object，没有数字时不包含sum、avg、min、max
+func (s Stats) ToValue() map[string]interface{} {
+	typeCounts := make(map[string]interface{}, len(s.Types))
+	for t, n := range s.Types {
+		typeCounts[string(t)] = n
+	}
+	res := map[string]interface{}{
+		"count":    s.Count,
+		"types":    typeCounts,
+		"distinct": s.Distinct,
+	}
+	if s.Sum != nil {
+		res["sum"] = s.Sum
+		res["avg"] = s.Avg
+		res["min"] = s.Min
+		res["max"] = s.Max
+	}
+	return res
+}
+
+// StatsValues 返回参与统计的值，数组统计其中的元素，其他值作为单个值统计
+func StatsValues(v interface{}) []interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		return val
+	case []map[string]interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = item
+		}
+		return res
+	default:
+		return []interface{}{v}
+	}
+}
+
+// ComputeStats 统计值的个数、类型、不同值的个数，以及数字的和、平均值、最小值、最大值
+// 整数的和按精确值计算，包含小数时按float64计算
+func ComputeStats(values []interface{}) (Stats, types.ZfError) {
+	stats := Stats{Count: len(values), Types: map[types.ValueType]int{}}
+	distinct, err := Distinct(values)
+	if err != nil {
+		return stats, err
+	}
+	stats.Distinct = len(distinct)
+
+	sum := new(big.Rat)
+	var floatSum float64
+	hasFloat := false
+	numbers := 0
+	for _, v := range values {
+		valueType, err := types.GetType(v)
+		if err != nil {
+			return stats, err
+		}
+		stats.Types[valueType]++
+		r, ok := toRat(v)
+		if !ok {
+			if valueType == types.Number {
+				// NaN和Inf
+				hasFloat = true
+				f, _ := v.(float64)
+				floatSum += f
+				numbers++
+				stats.Min, stats.Max = minMaxNumber(stats.Min, stats.Max, v)
+			}
+			continue
+		}
+		numbers++
+		if isFloat(v) {
+			hasFloat = true
+		}
+		sum.Add(sum, r)
+		f, _ := r.Float64()
+		floatSum += f
+		stats.Min, stats.Max = minMaxNumber(stats.Min, stats.Max, v)
+	}
+	if numbers == 0 {
+		return stats, nil
+	}
+	if hasFloat {
+		stats.Sum = floatSum
+		stats.Avg = floatSum / float64(numbers)
+		return stats, nil
+	}
+	stats.Sum = ratNumber(sum)
+	stats.Avg = ratNumber(new(big.Rat).Quo(sum, new(big.Rat).SetInt64(int64(numbers))))
+	return stats, nil
+}
+
+// ratNumber 整数不超过int64时返回int64，其他值返回float64，无法精确表示的整数返回json.Number
+func ratNumber(r *big.Rat) interface{} {
+	if r.IsInt() {
+		if r.Num().IsInt64() {
+			return r.Num().Int64()
+		}
+		return encjson.Number(r.Num().String())
+	}
+	f, _ := r.Float64()
+	return f
+}
+
+// minMaxNumber 用v更新最小值和最大值
+func minMaxNumber(min interface{}, max interface{}, v interface{}) (interface{}, interface{}) {
+	if min == nil || compareNumber(v, min) < 0 {
+		min = v
+	}
+	if max == nil || compareNumber(v, max) > 0 {
+		max = v
+	}
+	return min, max
+}
+
+// compareNumber 比较两个数字，包含小数时按float64比较，NaN最小
+func compareNumber(a interface{}, b interface{}) int {
+	x, okX := toRat(a)
+	y, okY := toRat(b)
+	if okX && okY && !isFloat(a) && !isFloat(b) {
+		return x.Cmp(y)
+	}
+	f, g := numberFloat(a, x, okX), numberFloat(b, y, okY)
+	switch {
+	case f < g || f != f && g == g:
+		return -1
+	case f > g || f == f && g != g:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func numberFloat(v interface{}, r *big.Rat, ok bool) float64 {
+	if ok {
+		f, _ := r.Float64()
+		return f
+	}
+	f, _ := v.(float64)
+	return f
+}
+
+// Distinct 按第一次出现的顺序返回不同的值，数字按数值比较
+func Distinct(values []interface{}) ([]interface{}, types.ZfError) {
+	var res []interface{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		key, err := valueKey(v)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// valueKey 值的规范化json，用于判断值是否相同
+func valueKey(v interface{}) (string, types.ZfError) {
+	if f, ok := v.(float64); ok && f != f {
+		return "NaN", nil
+	}
+	key, err := json.Canonicalize(v)
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
+// GroupCount 分组计数的一组
+type GroupCount struct {
+	Value interface{}
+	Count int
+}
+
+// ToValue 转换为可以按任意格式输出的object
+func (g GroupCount) ToValue() map[string]interface{} {
+	return map[string]interface{}{"value": g.Value, "count": g.Count}
+}
+
+// GroupCounts 按值分组计数，key不为空且不为 . 时按object中key的值分组，如 type、.meta.region
+// 结果按数量从多到少排列，数量相同时按第一次出现的顺序
+func GroupCounts(values []interface{}, key string) ([]GroupCount, types.ZfError) {
+	var groups []GroupCount
+	indexes := map[string]int{}
+	for _, v := range values {
+		if key != "" && key != "." {
+			v = lookupKey(v, key)
+		}
+		k, err := valueKey(v)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := indexes[k]
+		if !ok {
+			i = len(groups)
+			indexes[k] = i
+			groups = append(groups, GroupCount{Value: v})
+		}
+		groups[i].Count++
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count > groups[j].Count
+	})
+	return groups, nil
+}
+
+// lookupKey 按 . 分隔的键取出嵌套的值，不存在时为nil
+func lookupKey(v interface{}, key string) interface{} {
+	for _, k := range strings.Split(strings.TrimPrefix(key, "."), ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
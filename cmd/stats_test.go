@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	encjson "encoding/json"
+	"fmt"
+	yaml2 "github.com/izern/zf/codec/yaml"
+	"github.com/izern/zf/types"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_ComputeStats(t *testing.T) {
+	v, err := (&yaml2.YamlCodec{}).Unmarshal([]byte("[443, 80, 443, '443', null, {a: 1}, 8443]"))
+	assert.Nil(t, err)
+	stats, err := ComputeStats(StatsValues(v))
+	assert.Nil(t, err)
+	fmt.Println(stats.ToValue())
+	assert.Equal(t, 7, stats.Count)
+	assert.Equal(t, 6, stats.Distinct)
+	assert.Equal(t, map[types.ValueType]int{types.Number: 4, types.String: 1, types.Null: 1, types.Object: 1}, stats.Types)
+	assert.Equal(t, int64(9409), stats.Sum)
+	assert.Equal(t, 2352.25, stats.Avg)
+	assert.Equal(t, encjson.Number("80"), stats.Min)
+	assert.Equal(t, encjson.Number("8443"), stats.Max)
+
+	// 整数按精确值求和，包含小数时按float64计算
+	stats, err = ComputeStats([]interface{}{uint64(math.MaxUint64), int64(1), encjson.Number("2")})
+	assert.Nil(t, err)
+	assert.Equal(t, encjson.Number("18446744073709551618"), stats.Sum)
+	assert.Equal(t, uint64(math.MaxUint64), stats.Max)
+	stats, err = ComputeStats([]interface{}{1, 0.5, 1.0})
+	assert.Nil(t, err)
+	assert.Equal(t, 2.5, stats.Sum)
+	assert.Equal(t, 0.5, stats.Min)
+	assert.Equal(t, 2, stats.Distinct)
+
+	stats, err = ComputeStats([]interface{}{"a", "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"count": 2, "distinct": 2, "types": map[string]interface{}{"string": 2}}, stats.ToValue())
+}
+
+func Test_GroupCounts(t *testing.T) {
+	v, err := (&yaml2.YamlCodec{}).Unmarshal([]byte(`
+- {name: a, type: ss, meta: {region: hk}}
+- {name: b, type: vmess, meta: {region: us}}
+- {name: c, type: vmess}
+- {name: d, type: ss, meta: {region: hk}}
+- {name: e, type: vmess}
+`))
+	assert.Nil(t, err)
+	groups, err := GroupCounts(StatsValues(v), "type")
+	assert.Nil(t, err)
+	assert.Equal(t, []GroupCount{{Value: "vmess", Count: 3}, {Value: "ss", Count: 2}}, groups)
+	assert.Equal(t, map[string]interface{}{"value": "vmess", "count": 3}, groups[0].ToValue())
+
+	groups, err = GroupCounts(StatsValues(v), ".meta.region")
+	assert.Nil(t, err)
+	assert.Equal(t, []GroupCount{{Value: "hk", Count: 2}, {Value: nil, Count: 2}, {Value: "us", Count: 1}}, groups)
+
+	groups, err = GroupCounts([]interface{}{1, 1.0, "1", 2}, ".")
+	assert.Nil(t, err)
+	assert.Equal(t, []GroupCount{{Value: 1, Count: 2}, {Value: "1", Count: 1}, {Value: 2, Count: 1}}, groups)
+
+	distinct, err := Distinct([]interface{}{"b", "a", "b", 1, int64(1), []interface{}{1}, []interface{}{1.0}})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"b", "a", 1, []interface{}{1}}, distinct)
+}
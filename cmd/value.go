@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	encjson "encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// 命令行传入值的类型，ValueAuto按当前格式解析
+const (
+	ValueAuto   = ""
+	ValueString = "string"
+	ValueInt    = "int"
+	ValueFloat  = "float"
+	ValueBool   = "bool"
+	ValueNull   = "null"
+	ValueJSON   = "json"
+)
+
+var intPattern = regexp.MustCompile(`^[-+]?[0-9]+$`)
+
+// ParseTypedValue 按指定类型解析命令行传入的值
+// 整数和小数超出go数字类型的精度时保留为json.Number
+func ParseTypedValue(value string, valueType string) (interface{}, types.ZfError) {
+	switch valueType {
+	case ValueString:
+		return value, nil
+	case ValueInt:
+		text := strings.TrimSpace(value)
+		if !intPattern.MatchString(text) {
+			return nil, types.NewFormatError(value, ValueInt)
+		}
+		return util.ParseNumber(strings.TrimPrefix(text, "+")), nil
+	case ValueFloat:
+		text := strings.TrimPrefix(strings.TrimSpace(value), "+")
+		f, e := strconv.ParseFloat(text, 64)
+		if e != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			// 超出float64范围的数字按原文保留
+			if numErr, ok := e.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange && util.IsNumber(text) {
+				return encjson.Number(text), nil
+			}
+			return nil, types.NewFormatError(value, ValueFloat)
+		}
+		if util.IsNumber(text) && strings.ContainsAny(text, ".eE") {
+			return util.ParseNumber(text), nil
+		}
+		return f, nil
+	case ValueBool:
+		b, e := strconv.ParseBool(strings.TrimSpace(value))
+		if e != nil {
+			return nil, types.NewFormatError(value, ValueBool)
+		}
+		return b, nil
+	case ValueNull:
+		return nil, nil
+	case ValueJSON:
+		return (&json.JSONCodec{}).Unmarshal([]byte(value))
+	default:
+		return nil, types.NewUnSupportError("不支持的值类型:" + valueType)
+	}
+}
@@ -0,0 +1,126 @@
+package cbor
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/izern/zf/codec"
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+var (
+	encMode cbor.EncMode
+	decMode cbor.DecMode
+)
+
+func init() {
+	var err error
+	// 使用确定性编码，相同内容输出相同字节
+	encMode, err = cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		panic(err)
+	}
+	decMode, err = cbor.DecOptions{}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+}
+
+type CborCodec struct {
+}
+
+func (c *CborCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
+	return c.MarshalWithOptions(data, codec.DefaultMarshalOptions())
+}
+
+// MarshalWithOptions 二进制格式没有排版，忽略格式选项，object总是按键排序输出
+func (c *CborCodec) MarshalWithOptions(data interface{}, opts codec.MarshalOptions) ([]byte, types.ZfError) {
+	data = util.MapNumbers(data, toCborNumber)
+	result, e := encMode.Marshal(data)
+	if e != nil {
+		return nil, types.NewFormatError(e.Error(), "cbor")
+	}
+	return result, nil
+}
+
+func (c *CborCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var result interface{}
+	e := decMode.Unmarshal(data, &result)
+	if e != nil {
+		return nil, types.NewFormatError(e.Error(), "cbor")
+	}
+	return util.NormalizeValue(convertTags(result)), nil
+}
+
+// convertTags 去掉无法表示的tag，只保留其内容，超出uint64范围的bignum转换为json.Number
+func convertTags(v interface{}) interface{} {
+	switch val := v.(type) {
+	case cbor.Tag:
+		return convertTags(val.Content)
+	case big.Int:
+		if val.IsInt64() {
+			return val.Int64()
+		}
+		if val.IsUint64() {
+			return val.Uint64()
+		}
+		return json.Number(val.String())
+	case map[interface{}]interface{}:
+		for k, item := range val {
+			val[k] = convertTags(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = convertTags(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// toCborNumber 超出uint64范围的整数使用bignum，float64无法精确表示的小数按字符串输出
+func toCborNumber(n json.Number) interface{} {
+	if v, ok := util.ExactNumber(n); ok {
+		return v
+	}
+	if i, ok := new(big.Int).SetString(string(n), 10); ok {
+		return i
+	}
+	return string(n)
+}
+
+func (c *CborCodec) GetInfo() codec.CodecInfo {
+	return codec.CodecInfo{
+		Name:           "cbor",
+		FileExtensions: []string{".cbor"},
+		MimeTypes:      []string{"application/cbor"},
+		Capabilities: codec.CodecCapabilities{
+			SupportsObjects:    true,
+			SupportsArrays:     true,
+			SupportsPrimitives: true,
+			SupportsComments:   false,
+			IsBinary:           true,
+		},
+	}
+}
+
+func (c *CborCodec) CanHandle(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	// 顶层一般是map(major type 5)、array(major type 4)或self-describe tag
+	majorType := data[0] >> 5
+	if majorType != 4 && majorType != 5 && majorType != 6 {
+		return false
+	}
+	return cbor.Valid(data) == nil
+}
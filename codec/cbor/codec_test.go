@@ -0,0 +1,49 @@
+package cbor
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_Unmarshal(t *testing.T) {
+	// {"a":"Easy","b":{"c":20000000,"d":[3,4]}}
+	var data = []byte{
+		0xa2, 0x61, 'a', 0x64, 'E', 'a', 's', 'y',
+		0x61, 'b', 0xa2, 0x61, 'c', 0x1a, 0x01, 0x31, 0x2d, 0x00,
+		0x61, 'd', 0x82, 0x03, 0x04,
+	}
+	codec := &CborCodec{}
+	assert.True(t, codec.CanHandle(data))
+	result, err := codec.Unmarshal(data)
+	assert.Nil(t, err)
+	fmt.Println(result)
+	m, ok := result.(map[string]interface{})
+	assert.True(t, ok, "cbor map should decode to map[string]interface{}")
+	assert.Equal(t, "Easy", m["a"])
+	assert.IsType(t, map[string]interface{}{}, m["b"])
+}
+
+func TestCborCodec_Marshal(t *testing.T) {
+	codec := &CborCodec{}
+
+	marshal, err := codec.Marshal(map[string]interface{}{
+		"a": "Easy",
+		"b": map[string]interface{}{
+			"c": 2000000,
+			"d": true},
+	})
+	assert.Nil(t, err)
+	res, err := codec.Unmarshal(marshal)
+	assert.Nil(t, err)
+	fmt.Println(res)
+
+	// 确定性编码，map顺序不影响输出
+	again, err := codec.Marshal(res)
+	assert.Nil(t, err)
+	assert.Equal(t, marshal, again)
+}
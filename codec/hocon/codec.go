@@ -0,0 +1,240 @@
+package hocon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/izern/zf/codec"
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// HoconCodec HOCON格式，支持include、${}替换、object合并和 +=
+// BaseDir 为include相对路径的基准目录，为空时使用输入文件所在目录或当前目录
+type HoconCodec struct {
+	BaseDir string
+}
+
+func (h *HoconCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
+	return h.MarshalWithOptions(data, codec.DefaultMarshalOptions())
+}
+
+// MarshalWithOptions 默认缩进2个空格，Compact 时按json输出
+func (h *HoconCodec) MarshalWithOptions(data interface{}, opts codec.MarshalOptions) ([]byte, types.ZfError) {
+	if m, ok := data.(map[interface{}]interface{}); ok {
+		data = util.ConvertMap2String(m)
+	}
+	obj, ok := data.(map[string]interface{})
+	if !ok || opts.Compact {
+		// 根节点不是object时按json输出，json是合法的hocon
+		result, e := encodeJSON(data, opts.EscapeHTML)
+		if e != nil {
+			return nil, types.NewFormatError(e.Error(), "hocon")
+		}
+		return result, nil
+	}
+	w := &writer{indent: opts.IndentString("  "), escapeHTML: opts.EscapeHTML}
+	if err := w.writeFields(obj, 0); err != nil {
+		return nil, err
+	}
+	return []byte(w.sb.String()), nil
+}
+
+func (h *HoconCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	text := string(data)
+	p := newParser(text, h.baseDir(), nil, 0)
+	raw, err := p.parseDocument()
+	if err != nil {
+		// 不是键值对形式的内容可以作为单个值解析，如 set -v 传入的值
+		if p.structured {
+			return nil, err
+		}
+		single := newParser(text, h.baseDir(), nil, 0)
+		v, singleErr := single.parseSingleValue()
+		if singleErr != nil {
+			return nil, err
+		}
+		p = single
+		raw = v
+	}
+
+	result, _, err := newResolver(p.root).resolve(raw)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (h *HoconCodec) baseDir() string {
+	if h.BaseDir != "" {
+		return h.BaseDir
+	}
+	if file := util.InputFile(); file != "" {
+		return filepath.Dir(file)
+	}
+	return "."
+}
+
+var simpleKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func formatKey(k string) string {
+	if simpleKey.MatchString(k) {
+		return k
+	}
+	quoted, _ := json.Marshal(k)
+	return string(quoted)
+}
+
+// encodeJSON 标量值和非object的根节点使用json表示
+func encodeJSON(v interface{}, escapeHTML bool) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(escapeHTML)
+	if e := encoder.Encode(v); e != nil {
+		return nil, e
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// writer 按层级缩进输出hocon文本
+type writer struct {
+	sb         strings.Builder
+	indent     string
+	escapeHTML bool
+}
+
+func (w *writer) writeFields(obj map[string]interface{}, depth int) types.ZfError {
+	sb := &w.sb
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	indent := strings.Repeat(w.indent, depth)
+	for _, k := range keys {
+		sb.WriteString(indent)
+		sb.WriteString(formatKey(k))
+		if child, ok := toObject(obj[k]); ok {
+			sb.WriteString(" {\n")
+			if err := w.writeFields(child, depth+1); err != nil {
+				return err
+			}
+			sb.WriteString(indent)
+			sb.WriteString("}\n")
+			continue
+		}
+		sb.WriteString(" = ")
+		if err := w.writeValue(obj[k], depth); err != nil {
+			return err
+		}
+		sb.WriteString("\n")
+	}
+	return nil
+}
+
+func (w *writer) writeValue(v interface{}, depth int) types.ZfError {
+	sb := &w.sb
+	if obj, ok := toObject(v); ok {
+		sb.WriteString("{\n")
+		if err := w.writeFields(obj, depth+1); err != nil {
+			return err
+		}
+		sb.WriteString(strings.Repeat(w.indent, depth))
+		sb.WriteString("}")
+		return nil
+	}
+	if arr, ok := v.([]interface{}); ok {
+		if len(arr) == 0 {
+			sb.WriteString("[]")
+			return nil
+		}
+		if isScalarArray(arr) {
+			sb.WriteString("[")
+			for i, item := range arr {
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				if err := w.writeValue(item, depth+1); err != nil {
+					return err
+				}
+			}
+			sb.WriteString("]")
+			return nil
+		}
+		sb.WriteString("[\n")
+		indent := strings.Repeat(w.indent, depth+1)
+		for _, item := range arr {
+			sb.WriteString(indent)
+			if err := w.writeValue(item, depth+1); err != nil {
+				return err
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString(strings.Repeat(w.indent, depth))
+		sb.WriteString("]")
+		return nil
+	}
+	result, e := encodeJSON(v, w.escapeHTML)
+	if e != nil {
+		return types.NewFormatError(fmt.Sprintf("%v: %s", v, e.Error()), "hocon")
+	}
+	sb.Write(result)
+	return nil
+}
+
+// isScalarArray 元素都不是object或array时，数组在一行内输出
+func isScalarArray(arr []interface{}) bool {
+	for _, item := range arr {
+		switch item.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+func toObject(v interface{}) (map[string]interface{}, bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return val, true
+	case map[interface{}]interface{}:
+		return util.ConvertMap2String(val), true
+	default:
+		return nil, false
+	}
+}
+
+func (h *HoconCodec) GetInfo() codec.CodecInfo {
+	return codec.CodecInfo{
+		Name:           "hocon",
+		FileExtensions: []string{".conf", ".hocon"},
+		MimeTypes:      []string{"application/hocon"},
+		Capabilities: codec.CodecCapabilities{
+			SupportsObjects:    true,
+			SupportsArrays:     true,
+			SupportsPrimitives: true,
+			SupportsComments:   true,
+		},
+	}
+}
+
+func (h *HoconCodec) CanHandle(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	_, err := h.Unmarshal(data)
+	return err == nil
+}
@@ -0,0 +1,119 @@
+package hocon
+
+import (
+	"fmt"
+	codec2 "github.com/izern/zf/codec"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_Unmarshal(t *testing.T) {
+	var data = `
+# comment
+a = Easy
+b {
+  c = 20000000
+  d = [3, 4]
+}
+b.d += 5
+b { e = ${a}" and "${b.c} }
+path = /usr/bin
+path = ${path}":/opt/bin"
+opt = ${?NOT_DEFINED_ANYWHERE}
+base { x = 1, y { z = 2 } }
+derived = ${base} { y.w = 3 }
+`
+	codec := &HoconCodec{}
+	result, err := codec.Unmarshal([]byte(data))
+	assert.Nil(t, err)
+	fmt.Println(result)
+	m := result.(map[string]interface{})
+	b := m["b"].(map[string]interface{})
+	assert.Equal(t, "Easy", m["a"])
+	assert.Equal(t, int64(20000000), b["c"])
+	assert.Equal(t, []interface{}{int64(3), int64(4), int64(5)}, b["d"])
+	assert.Equal(t, "Easy and 20000000", b["e"])
+	assert.Equal(t, "/usr/bin:/opt/bin", m["path"])
+	_, ok := m["opt"]
+	assert.False(t, ok, "undefined optional substitution should be removed")
+	assert.Equal(t, map[string]interface{}{
+		"x": int64(1),
+		"y": map[string]interface{}{"z": int64(2), "w": int64(3)},
+	}, m["derived"])
+
+	_, err = codec.Unmarshal([]byte("a = ${missing}"))
+	assert.NotNil(t, err)
+	_, err = codec.Unmarshal([]byte("a = ${b}\nb = ${a}"))
+	assert.NotNil(t, err)
+
+	// 非键值对的内容作为单个值解析
+	value, err := codec.Unmarshal([]byte("web server"))
+	assert.Nil(t, err)
+	assert.Equal(t, "web server", value)
+}
+
+func Test_Include(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "defaults.conf"), []byte(`
+server { host = localhost, port = 80 }
+url = "http://"${server.host}
+`), 0644)
+	assert.Nil(t, err)
+
+	codec := &HoconCodec{BaseDir: dir}
+	result, zfErr := codec.Unmarshal([]byte(`
+include "defaults"
+server.port = 8080
+nested { include file("defaults.conf") }
+include "missing.conf"
+`))
+	assert.Nil(t, zfErr)
+	m := result.(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"host": "localhost", "port": int64(8080)}, m["server"])
+	assert.Equal(t, "http://localhost", m["url"])
+	assert.Equal(t, "http://localhost", m["nested"].(map[string]interface{})["url"])
+
+	_, zfErr = codec.Unmarshal([]byte(`include required("missing.conf")`))
+	assert.NotNil(t, zfErr)
+}
+
+func TestHoconCodec_Marshal(t *testing.T) {
+	codec := &HoconCodec{}
+
+	marshal, err := codec.Marshal(map[string]interface{}{
+		"a": "Easy",
+		"b": map[string]interface{}{
+			"c":   2000000,
+			"d":   true,
+			"e.f": []interface{}{1, "x,y", nil}},
+	})
+	assert.Nil(t, err)
+	fmt.Println(string(marshal))
+	res, err := codec.Unmarshal(marshal)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(1), "x,y", nil}, res.(map[string]interface{})["b"].(map[string]interface{})["e.f"])
+}
+
+func TestHoconCodec_MarshalWithOptions(t *testing.T) {
+	codec := &HoconCodec{}
+	data := map[string]interface{}{"a": map[string]interface{}{"b": "<x>"}}
+
+	opts := codec2.DefaultMarshalOptions()
+	opts.Tab = true
+	opts.EscapeHTML = false
+	marshal, err := codec.MarshalWithOptions(data, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "a {\n\tb = \"<x>\"\n}\n", string(marshal))
+
+	opts.Tab = false
+	opts.Compact = true
+	marshal, err = codec.MarshalWithOptions(data, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{"b":"<x>"}}`, string(marshal))
+}
@@ -0,0 +1,819 @@
+package hocon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+// 最大include嵌套层数，防止循环引用
+const maxIncludeDepth = 50
+
+// 以下类型是解析后、替换前的中间节点
+
+// subst 替换表达式 ${a.b} 或 ${?a.b}
+type subst struct {
+	path     []string
+	fallback []string // include文件中的替换，先按include位置查找，找不到再从根查找
+	optional bool
+	self     bool        // 引用了自身路径，取赋值前的值
+	selfPath []string    // 自身字段的完整路径
+	prev     interface{} // 赋值前的值
+	hasPrev  bool
+}
+
+// whitespace 值拼接时两个值之间的空白，只在字符串拼接时保留
+type whitespace string
+
+// concat 值拼接，如 foo bar、${a} "/bin"、[1] [2]、{a:1} {b:2}
+type concat struct {
+	parts []interface{}
+}
+
+// merge 已有的值与新的object合并，已有的值在解析时还不能确定
+type merge struct {
+	base interface{}
+	over interface{}
+}
+
+type parser struct {
+	src     []rune
+	pos     int
+	line    int
+	baseDir string
+	prefix  []string // include位置的路径
+	root    map[string]interface{}
+	depth   int
+	// 是否已识别出文档结构(键值对、include、括号)，用于判断能否作为单个值解析
+	structured bool
+}
+
+func newParser(text string, baseDir string, prefix []string, depth int) *parser {
+	return &parser{
+		src:     []rune(text),
+		line:    1,
+		baseDir: baseDir,
+		prefix:  prefix,
+		depth:   depth,
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) types.ZfError {
+	return types.NewFormatError(fmt.Sprintf("第%d行: %s", p.line, fmt.Sprintf(format, args...)), "hocon")
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) peekAt(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	rs := []rune(s)
+	if p.pos+len(rs) > len(p.src) {
+		return false
+	}
+	for i, r := range rs {
+		if p.src[p.pos+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) next() rune {
+	r := p.src[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+func isInlineSpace(r rune) bool {
+	return r != '\n' && (unicode.IsSpace(r) || r == '\uFEFF')
+}
+
+func (p *parser) isCommentStart() bool {
+	return p.peek() == '#' || p.hasPrefix("//")
+}
+
+func (p *parser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+}
+
+// skipInline 跳过同一行内的空白和注释
+func (p *parser) skipInline() {
+	for !p.eof() {
+		if isInlineSpace(p.peek()) {
+			p.next()
+		} else if p.isCommentStart() {
+			p.skipComment()
+		} else {
+			return
+		}
+	}
+}
+
+// skipSeparators 跳过空白、换行、逗号和注释
+func (p *parser) skipSeparators() {
+	for !p.eof() {
+		r := p.peek()
+		if unicode.IsSpace(r) || r == ',' || r == '\uFEFF' {
+			p.next()
+		} else if p.isCommentStart() {
+			p.skipComment()
+		} else {
+			return
+		}
+	}
+}
+
+// 不能出现在未加引号字符串中的字符
+func isForbidden(r rune) bool {
+	return strings.ContainsRune("$\"{}[]:=,+#`^?!@*&\\", r)
+}
+
+// parseDocument 解析整个文档，根节点可以是object或array
+func (p *parser) parseDocument() (interface{}, types.ZfError) {
+	p.root = make(map[string]interface{})
+	p.skipSeparators()
+	if p.peek() == '[' {
+		p.structured = true
+		v, err := p.parseArray()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSeparators()
+		if !p.eof() {
+			return nil, p.errorf("数组后存在多余内容")
+		}
+		return v, nil
+	}
+	braced := false
+	if p.peek() == '{' {
+		braced = true
+		p.structured = true
+		p.next()
+	}
+	err := p.parseObjectBody(p.root, nil, braced)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSeparators()
+	if !p.eof() {
+		return nil, p.errorf("存在多余内容")
+	}
+	return p.root, nil
+}
+
+// parseSingleValue 把整个文本当作一个值解析，用于 -v 等传入的值
+func (p *parser) parseSingleValue() (interface{}, types.ZfError) {
+	p.root = make(map[string]interface{})
+	p.skipInline()
+	v, err := p.parseValue(nil)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSeparators()
+	if !p.eof() {
+		return nil, p.errorf("存在多余内容")
+	}
+	return v, nil
+}
+
+// parseObjectBody 解析object的字段，path是该object相对根节点的路径
+func (p *parser) parseObjectBody(obj map[string]interface{}, path []string, braced bool) types.ZfError {
+	for {
+		p.skipSeparators()
+		if p.eof() {
+			if braced {
+				return p.errorf("缺少 }")
+			}
+			return nil
+		}
+		if p.peek() == '}' {
+			if !braced {
+				return p.errorf("多余的 }")
+			}
+			p.next()
+			return nil
+		}
+		if p.isInclude() {
+			if err := p.parseInclude(obj, path); err != nil {
+				return err
+			}
+		} else if err := p.parseField(obj, path); err != nil {
+			return err
+		}
+		// 字段之间需要用逗号或换行分隔
+		p.skipInline()
+		if !p.eof() && p.peek() != ',' && p.peek() != '\n' && p.peek() != '}' {
+			return p.errorf("字段之间缺少分隔符，遇到 %q", p.peek())
+		}
+	}
+}
+
+func (p *parser) parseField(obj map[string]interface{}, path []string) types.ZfError {
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipInline()
+	fullPath := appendPath(path, key...)
+
+	appendMode := false
+	switch {
+	case p.peek() == '{':
+	case p.peek() == '=' || p.peek() == ':':
+		p.next()
+	case p.hasPrefix("+="):
+		p.pos += 2
+		appendMode = true
+	default:
+		return p.errorf("键%s后缺少 = 或 :", strings.Join(key, "."))
+	}
+	p.structured = true
+	p.skipInline()
+	value, err := p.parseValue(fullPath)
+	if err != nil {
+		return err
+	}
+	if appendMode {
+		// a += b 等价于 a = ${?a} [b]
+		value = &concat{parts: []interface{}{
+			&subst{path: fullPath, optional: true},
+			[]interface{}{value},
+		}}
+	}
+	p.assign(obj, path, key, value)
+	return nil
+}
+
+// parseKey 解析键，支持点号分隔的路径和带引号的键
+func (p *parser) parseKey() ([]string, types.ZfError) {
+	var segments []string
+	var cur strings.Builder
+	hasContent := false
+	for {
+		if p.eof() {
+			return nil, p.errorf("键不完整")
+		}
+		r := p.peek()
+		switch {
+		case r == '"':
+			s, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			cur.WriteString(s)
+			hasContent = true
+		case r == '.':
+			if !hasContent {
+				return nil, p.errorf("键中存在空的路径段")
+			}
+			p.next()
+			segments = append(segments, cur.String())
+			cur.Reset()
+			hasContent = false
+		case isInlineSpace(r):
+			start := p.pos
+			for !p.eof() && isInlineSpace(p.peek()) {
+				p.next()
+			}
+			if p.peek() == '=' || p.peek() == ':' || p.peek() == '{' || p.hasPrefix("+=") {
+				continue
+			}
+			cur.WriteString(string(p.src[start:p.pos]))
+		case r == '=' || r == ':' || r == '{' || (r == '+' && p.peekAt(1) == '='):
+			if !hasContent {
+				return nil, p.errorf("缺少键")
+			}
+			return append(segments, cur.String()), nil
+		case r == '\n' || p.isCommentStart() || isForbidden(r):
+			return nil, p.errorf("键中存在非法字符 %q", r)
+		default:
+			for !p.eof() {
+				r = p.peek()
+				if r == '.' || unicode.IsSpace(r) || isForbidden(r) || p.hasPrefix("//") {
+					break
+				}
+				cur.WriteRune(p.next())
+			}
+			hasContent = true
+		}
+	}
+}
+
+// parseValue 解析一个值，多个相邻的值会拼接在一起
+func (p *parser) parseValue(path []string) (interface{}, types.ZfError) {
+	var parts []interface{}
+	for !p.eof() {
+		r := p.peek()
+		if r == '\n' || r == ',' || r == '}' || r == ']' || p.isCommentStart() {
+			break
+		}
+		switch {
+		case isInlineSpace(r):
+			start := p.pos
+			for !p.eof() && isInlineSpace(p.peek()) {
+				p.next()
+			}
+			parts = append(parts, whitespace(p.src[start:p.pos]))
+		case r == '"':
+			s, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s)
+		case r == '{':
+			p.next()
+			obj := make(map[string]interface{})
+			if err := p.parseObjectBody(obj, path, true); err != nil {
+				return nil, err
+			}
+			parts = append(parts, obj)
+		case r == '[':
+			arr, err := p.parseArray()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, arr)
+		case r == '$':
+			s, err := p.parseSubst()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s)
+		case isForbidden(r):
+			return nil, p.errorf("值中存在非法字符 %q", r)
+		default:
+			start := p.pos
+			for !p.eof() {
+				r = p.peek()
+				if unicode.IsSpace(r) || isForbidden(r) || p.hasPrefix("//") {
+					break
+				}
+				p.next()
+			}
+			parts = append(parts, unquoted(string(p.src[start:p.pos])))
+		}
+	}
+
+	// 去掉首尾空白
+	for len(parts) > 0 {
+		if _, ok := parts[0].(whitespace); !ok {
+			break
+		}
+		parts = parts[1:]
+	}
+	for len(parts) > 0 {
+		if _, ok := parts[len(parts)-1].(whitespace); !ok {
+			break
+		}
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) == 0 {
+		return nil, p.errorf("缺少值")
+	}
+	if len(parts) == 1 {
+		if u, ok := parts[0].(unquoted); ok {
+			return u.value(), nil
+		}
+		return parts[0], nil
+	}
+	for i, part := range parts {
+		if u, ok := part.(unquoted); ok {
+			parts[i] = string(u)
+		}
+	}
+	return &concat{parts: parts}, nil
+}
+
+// unquoted 未加引号的文本，单独出现时可能是数字、布尔或null
+type unquoted string
+
+func (u unquoted) value() interface{} {
+	s := string(u)
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if isNumber(s) {
+		// 无法用int64、uint64、float64精确表示时保留为json.Number
+		return util.ParseNumber(s)
+	}
+	return s
+}
+
+// isNumber 按json的数字格式判断
+func isNumber(s string) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if digits() == 0 {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+func (p *parser) parseArray() ([]interface{}, types.ZfError) {
+	p.next()
+	result := make([]interface{}, 0)
+	for {
+		p.skipSeparators()
+		if p.eof() {
+			return nil, p.errorf("缺少 ]")
+		}
+		if p.peek() == ']' {
+			p.next()
+			return result, nil
+		}
+		v, err := p.parseValue(nil)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+		p.skipInline()
+		if !p.eof() && p.peek() != ',' && p.peek() != '\n' && p.peek() != ']' {
+			return nil, p.errorf("数组元素之间缺少分隔符，遇到 %q", p.peek())
+		}
+	}
+}
+
+// parseQuoted 解析带引号的字符串，支持 """多行字符串"""
+func (p *parser) parseQuoted() (string, types.ZfError) {
+	if p.hasPrefix(`"""`) {
+		p.pos += 3
+		var sb strings.Builder
+		for {
+			if p.eof() {
+				return "", p.errorf(`缺少结束的 """`)
+			}
+			if p.hasPrefix(`"""`) {
+				// 结尾多余的引号属于字符串内容
+				for p.peekAt(3) == '"' {
+					sb.WriteRune(p.next())
+				}
+				p.pos += 3
+				return sb.String(), nil
+			}
+			sb.WriteRune(p.next())
+		}
+	}
+
+	start := p.pos
+	p.next()
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("字符串缺少结束的引号")
+		}
+		r := p.next()
+		if r == '\\' {
+			if p.eof() {
+				return "", p.errorf("字符串缺少结束的引号")
+			}
+			p.next()
+			continue
+		}
+		if r == '"' {
+			break
+		}
+	}
+	s, err := strconv.Unquote(string(p.src[start:p.pos]))
+	if err != nil {
+		return "", p.errorf("无效的字符串 %s", string(p.src[start:p.pos]))
+	}
+	return s, nil
+}
+
+// parseSubst 解析 ${path} 或 ${?path}
+func (p *parser) parseSubst() (*subst, types.ZfError) {
+	if !p.hasPrefix("${") {
+		return nil, p.errorf("值中存在非法字符 '$'")
+	}
+	p.pos += 2
+	s := &subst{}
+	if p.peek() == '?' {
+		p.next()
+		s.optional = true
+	}
+	p.skipInline()
+	key, err := p.parseSubstPath()
+	if err != nil {
+		return nil, err
+	}
+	s.path = key
+	if len(p.prefix) > 0 {
+		s.path = appendPath(p.prefix, key...)
+		s.fallback = key
+	}
+	return s, nil
+}
+
+func (p *parser) parseSubstPath() ([]string, types.ZfError) {
+	var segments []string
+	var cur strings.Builder
+	hasContent := false
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return nil, p.errorf("替换表达式缺少 }")
+		}
+		r := p.peek()
+		switch {
+		case r == '}':
+			p.next()
+			if !hasContent {
+				return nil, p.errorf("替换表达式路径为空")
+			}
+			return append(segments, strings.TrimRight(cur.String(), " \t")), nil
+		case r == '"':
+			s, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			cur.WriteString(s)
+			hasContent = true
+		case r == '.':
+			p.next()
+			segments = append(segments, cur.String())
+			cur.Reset()
+			hasContent = false
+		default:
+			cur.WriteRune(p.next())
+			hasContent = true
+		}
+	}
+}
+
+// isInclude 判断当前字段是否为include语句
+func (p *parser) isInclude() bool {
+	if !p.hasPrefix("include") {
+		return false
+	}
+	i := len("include")
+	if !isInlineSpace(p.peekAt(i)) {
+		return false
+	}
+	for isInlineSpace(p.peekAt(i)) {
+		i++
+	}
+	return p.peekAt(i) == '"' || p.peekAt(i) == 'f' || p.peekAt(i) == 'r' || p.peekAt(i) == 'u' || p.peekAt(i) == 'c'
+}
+
+// parseInclude 解析 include "file"、include file("file")、include required(file("file"))
+// 只支持本地文件，相对路径相对于当前文件所在目录
+func (p *parser) parseInclude(obj map[string]interface{}, path []string) types.ZfError {
+	p.pos += len("include")
+	p.structured = true
+	p.skipInline()
+
+	required := false
+	closing := 0
+	open := func(name string) bool {
+		if p.hasPrefix(name + "(") {
+			p.pos += len(name) + 1
+			closing++
+			p.skipInline()
+			return true
+		}
+		return false
+	}
+	if open("required") {
+		required = true
+	}
+	if open("url") || open("classpath") {
+		return p.errorf("只支持include本地文件")
+	}
+	open("file")
+	if p.peek() != '"' {
+		return p.errorf("include缺少文件名")
+	}
+	name, err := p.parseQuoted()
+	if err != nil {
+		return err
+	}
+	for ; closing > 0; closing-- {
+		p.skipInline()
+		if p.peek() != ')' {
+			return p.errorf("include缺少 )")
+		}
+		p.next()
+	}
+
+	if p.depth >= maxIncludeDepth {
+		return p.errorf("include嵌套过深，可能存在循环引用: %s", name)
+	}
+	file, found := p.findInclude(name)
+	if !found {
+		if required {
+			return p.errorf("找不到include文件: %s", name)
+		}
+		return nil
+	}
+	content, e := ioutil.ReadFile(file)
+	if e != nil {
+		return p.errorf("读取include文件失败: %s", e.Error())
+	}
+
+	child := newParser(string(content), filepath.Dir(file), appendPath(p.prefix, path...), p.depth+1)
+	included, err := child.parseDocument()
+	if err != nil {
+		return types.NewFormatError(fmt.Sprintf("%s: %s", file, err.Error().Error()), "hocon")
+	}
+	includedObj, ok := included.(map[string]interface{})
+	if !ok {
+		return p.errorf("include文件的根节点必须是object: %s", name)
+	}
+	for k, v := range includedObj {
+		p.assign(obj, path, []string{k}, v)
+	}
+	return nil
+}
+
+// findInclude 查找include文件，没有扩展名时依次尝试 .conf 和 .json
+func (p *parser) findInclude(name string) (string, bool) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(p.baseDir, name)
+	}
+	candidates := []string{name}
+	if filepath.Ext(name) == "" {
+		candidates = append(candidates, name+".conf", name+".json")
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// assign 给object中的键赋值，object类型的值与已有的object合并
+func (p *parser) assign(obj map[string]interface{}, path []string, key []string, value interface{}) {
+	for _, segment := range key[:len(key)-1] {
+		path = appendPath(path, segment)
+		switch child := obj[segment].(type) {
+		case map[string]interface{}:
+			obj = child
+		case *subst, *concat, *merge:
+			next := make(map[string]interface{})
+			obj[segment] = &merge{base: child, over: next}
+			obj = next
+		default:
+			next := make(map[string]interface{})
+			obj[segment] = next
+			obj = next
+		}
+	}
+
+	last := key[len(key)-1]
+	fullPath := appendPath(path, last)
+	prev, hasPrev := obj[last]
+	if !hasPrev {
+		prev, hasPrev = lookupRaw(p.root, fullPath)
+	}
+	markSelfReference(value, fullPath, copyRaw(prev), hasPrev)
+
+	newObj, isObj := value.(map[string]interface{})
+	switch prevV := obj[last].(type) {
+	case map[string]interface{}:
+		if isObj {
+			for k, v := range newObj {
+				p.assign(prevV, fullPath, []string{k}, v)
+			}
+			return
+		}
+	case *subst, *concat, *merge:
+		if isObj {
+			obj[last] = &merge{base: prevV, over: newObj}
+			return
+		}
+	}
+	obj[last] = value
+}
+
+// markSelfReference 标记引用自身路径的替换，解析时使用赋值前的值
+func markSelfReference(v interface{}, fullPath []string, prev interface{}, hasPrev bool) {
+	switch val := v.(type) {
+	case *subst:
+		if !val.self && hasPathPrefix(val.path, fullPath) {
+			val.self = true
+			val.selfPath = fullPath
+			val.prev = prev
+			val.hasPrev = hasPrev
+		}
+	case *concat:
+		for _, part := range val.parts {
+			markSelfReference(part, fullPath, prev, hasPrev)
+		}
+	case *merge:
+		markSelfReference(val.base, fullPath, prev, hasPrev)
+		markSelfReference(val.over, fullPath, prev, hasPrev)
+	case []interface{}:
+		for _, item := range val {
+			markSelfReference(item, fullPath, prev, hasPrev)
+		}
+	}
+}
+
+// lookupRaw 在未替换的树中查找路径对应的值
+func lookupRaw(root map[string]interface{}, path []string) (interface{}, bool) {
+	var cur interface{} = root
+	for _, segment := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// copyRaw 复制object和array，避免后续赋值修改了保存的旧值
+func copyRaw(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			res[k] = copyRaw(item)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = copyRaw(item)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+func hasPathPrefix(path []string, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendPath(path []string, segments ...string) []string {
+	res := make([]string, 0, len(path)+len(segments))
+	res = append(res, path...)
+	return append(res, segments...)
+}
@@ -0,0 +1,250 @@
+package hocon
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/izern/zf/types"
+)
+
+// resolver 在整个文档解析完成后处理替换表达式和值拼接
+type resolver struct {
+	root      map[string]interface{}
+	resolving map[*subst]bool
+}
+
+func newResolver(root map[string]interface{}) *resolver {
+	return &resolver{root: root, resolving: make(map[*subst]bool)}
+}
+
+// resolve 返回替换后的值，第二个返回值为false表示可选替换未定义，应忽略该值
+func (r *resolver) resolve(v interface{}) (interface{}, bool, types.ZfError) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			rv, ok, err := r.resolve(item)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				res[k] = rv
+			}
+		}
+		return res, true, nil
+	case []interface{}:
+		res := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			rv, ok, err := r.resolve(item)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				res = append(res, rv)
+			}
+		}
+		return res, true, nil
+	case *subst:
+		return r.resolveSubst(val)
+	case *concat:
+		return r.resolveConcat(val)
+	case *merge:
+		base, baseOk, err := r.resolve(val.base)
+		if err != nil {
+			return nil, false, err
+		}
+		over, overOk, err := r.resolve(val.over)
+		if err != nil {
+			return nil, false, err
+		}
+		if !overOk {
+			return base, baseOk, nil
+		}
+		baseMap, ok1 := base.(map[string]interface{})
+		overMap, ok2 := over.(map[string]interface{})
+		if baseOk && ok1 && ok2 {
+			return mergeObjects(baseMap, overMap), true, nil
+		}
+		return over, true, nil
+	case whitespace:
+		return string(val), true, nil
+	default:
+		return v, true, nil
+	}
+}
+
+func (r *resolver) resolveSubst(s *subst) (interface{}, bool, types.ZfError) {
+	if r.resolving[s] {
+		return nil, false, types.NewFormatError("替换存在循环引用: ${"+strings.Join(s.path, ".")+"}", "hocon")
+	}
+	r.resolving[s] = true
+	defer delete(r.resolving, s)
+
+	if s.self {
+		// 引用自身时，从赋值前的值中查找
+		if s.hasPrev {
+			if v, ok, err := r.lookup(s.prev, s.path[len(s.selfPath):]); err != nil || ok {
+				return v, ok, err
+			}
+		}
+	} else {
+		if v, ok, err := r.lookup(r.root, s.path); err != nil || ok {
+			return v, ok, err
+		}
+		if s.fallback != nil {
+			if v, ok, err := r.lookup(r.root, s.fallback); err != nil || ok {
+				return v, ok, err
+			}
+		}
+	}
+
+	// 找不到时使用同名环境变量
+	name := s.path
+	if s.fallback != nil {
+		name = s.fallback
+	}
+	if env, ok := os.LookupEnv(strings.Join(name, ".")); ok {
+		return env, true, nil
+	}
+	if s.optional {
+		return nil, false, nil
+	}
+	return nil, false, types.NewFormatError("无法解析替换: ${"+strings.Join(name, ".")+"}", "hocon")
+}
+
+// lookup 从指定节点开始按路径查找值，途经的节点先完成替换
+func (r *resolver) lookup(from interface{}, path []string) (interface{}, bool, types.ZfError) {
+	cur := from
+	for _, segment := range path {
+		switch cur.(type) {
+		case *subst, *concat, *merge:
+			v, ok, err := r.resolve(cur)
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			cur = v
+		}
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false, nil
+		}
+		cur, ok = m[segment]
+		if !ok {
+			return nil, false, nil
+		}
+	}
+	return r.resolve(cur)
+}
+
+func (r *resolver) resolveConcat(c *concat) (interface{}, bool, types.ZfError) {
+	values := make([]interface{}, 0, len(c.parts))
+	for _, part := range c.parts {
+		v, ok, err := r.resolve(part)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			continue
+		}
+		if _, isSpace := part.(whitespace); isSpace {
+			values = append(values, whitespace(v.(string)))
+			continue
+		}
+		values = append(values, v)
+	}
+
+	// 可选替换未定义时，去掉两侧多余的空白
+	for len(values) > 0 {
+		if _, isSpace := values[0].(whitespace); !isSpace {
+			break
+		}
+		values = values[1:]
+	}
+	for len(values) > 0 {
+		if _, isSpace := values[len(values)-1].(whitespace); !isSpace {
+			break
+		}
+		values = values[:len(values)-1]
+	}
+	if len(values) == 0 {
+		return nil, false, nil
+	}
+	if len(values) == 1 {
+		return values[0], true, nil
+	}
+
+	var arrays, objects, others int
+	for _, v := range values {
+		switch v.(type) {
+		case whitespace:
+		case []interface{}:
+			arrays++
+		case map[string]interface{}:
+			objects++
+		default:
+			others++
+		}
+	}
+
+	switch {
+	case arrays > 0 && objects+others == 0:
+		result := make([]interface{}, 0)
+		for _, v := range values {
+			if arr, ok := v.([]interface{}); ok {
+				result = append(result, arr...)
+			}
+		}
+		return result, true, nil
+	case objects > 0 && arrays+others == 0:
+		result := make(map[string]interface{})
+		for _, v := range values {
+			if obj, ok := v.(map[string]interface{}); ok {
+				result = mergeObjects(result, obj)
+			}
+		}
+		return result, true, nil
+	case arrays+objects > 0:
+		return nil, false, types.NewFormatError(fmt.Sprintf("无法拼接不同类型的值: %v", values), "hocon")
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		sb.WriteString(toConcatString(v))
+	}
+	return sb.String(), true, nil
+}
+
+func toConcatString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case whitespace:
+		return string(val)
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// mergeObjects 合并两个object，同名的object递归合并，其他类型的值以over为准
+func mergeObjects(base map[string]interface{}, over map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(base)+len(over))
+	for k, v := range base {
+		res[k] = v
+	}
+	for k, v := range over {
+		baseChild, ok1 := res[k].(map[string]interface{})
+		overChild, ok2 := v.(map[string]interface{})
+		if ok1 && ok2 {
+			res[k] = mergeObjects(baseChild, overChild)
+		} else {
+			res[k] = v
+		}
+	}
+	return res
+}
@@ -0,0 +1,191 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// Canonicalize 按RFC 8785 (JSON Canonicalization Scheme) 输出json
+// object按键的UTF-16编码排序，数字按ECMAScript规则格式化，字符串只转义必要的字符
+// 相同内容的文档无论来源格式和键顺序，输出的字节都相同，可以用于计算摘要和签名
+func Canonicalize(data interface{}) ([]byte, types.ZfError) {
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v interface{}) types.ZfError {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(val))
+	case string:
+		writeCanonicalString(buf, val)
+	case float64:
+		return writeCanonicalNumber(buf, val)
+	case float32:
+		return writeCanonicalNumber(buf, float64(val))
+	case int:
+		return writeCanonicalNumber(buf, float64(val))
+	case int8:
+		return writeCanonicalNumber(buf, float64(val))
+	case int16:
+		return writeCanonicalNumber(buf, float64(val))
+	case int32:
+		return writeCanonicalNumber(buf, float64(val))
+	case int64:
+		return writeCanonicalNumber(buf, float64(val))
+	case uint:
+		return writeCanonicalNumber(buf, float64(val))
+	case uint8:
+		return writeCanonicalNumber(buf, float64(val))
+	case uint16:
+		return writeCanonicalNumber(buf, float64(val))
+	case uint32:
+		return writeCanonicalNumber(buf, float64(val))
+	case uint64:
+		return writeCanonicalNumber(buf, float64(val))
+	case json.Number:
+		f, e := val.Float64()
+		if e != nil {
+			return types.NewFormatError(e.Error(), "json")
+		}
+		return writeCanonicalNumber(buf, f)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, k)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, val[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case map[interface{}]interface{}:
+		return writeCanonical(buf, util.ConvertMap2String(val))
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case []map[string]interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = item
+		}
+		return writeCanonical(buf, items)
+	default:
+		// 其他类型(如时间)先按json序列化，再按通用结构规范化
+		data, e := json.Marshal(val)
+		if e != nil {
+			return types.NewFormatError(e.Error(), "json")
+		}
+		var generic interface{}
+		if e = json.Unmarshal(data, &generic); e != nil {
+			return types.NewFormatError(e.Error(), "json")
+		}
+		return writeCanonical(buf, generic)
+	}
+	return nil
+}
+
+// writeCanonicalNumber 与ECMAScript的Number.prototype.toString结果一致
+func writeCanonicalNumber(buf *bytes.Buffer, f float64) types.ZfError {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return types.NewUnSupportError(fmt.Sprintf("规范化json不支持的数字: %v", f))
+	}
+	if f == 0 {
+		// -0 也输出为 0
+		buf.WriteByte('0')
+		return nil
+	}
+	if f < 0 {
+		buf.WriteByte('-')
+		f = -f
+	}
+	format := byte('e')
+	if f >= 1e-6 && f < 1e21 {
+		format = 'f'
+	}
+	s := strconv.FormatFloat(f, format, -1, 64)
+	// go输出的指数至少两位，如 1e-07，ECMAScript为 1e-7
+	if i := strings.IndexByte(s, 'e'); i > 0 && s[i+2] == '0' {
+		s = s[:i+2] + s[i+3:]
+	}
+	buf.WriteString(s)
+	return nil
+}
+
+// writeCanonicalString 只转义引号、反斜杠和控制字符，其他字符原样输出
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// lessUTF16 按UTF-16编码单元比较字符串
+func lessUTF16(a, b string) bool {
+	ua := utf16.Encode([]rune(a))
+	ub := utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
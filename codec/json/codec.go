@@ -1,7 +1,9 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"github.com/izern/zf/codec"
 	"github.com/izern/zf/types"
 	"strings"
@@ -15,11 +17,25 @@ type JSONCodec struct {
 }
 
 func (j *JSONCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
-	result, e := json.Marshal(data)
+	return j.MarshalWithOptions(data, codec.DefaultMarshalOptions())
+}
+
+// MarshalWithOptions 默认输出紧凑格式，指定缩进或tab时换行缩进输出，Canonical 时按RFC 8785输出
+func (j *JSONCodec) MarshalWithOptions(data interface{}, opts codec.MarshalOptions) ([]byte, types.ZfError) {
+	if opts.Canonical {
+		return Canonicalize(data)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(opts.EscapeHTML)
+	if !opts.Compact {
+		encoder.SetIndent("", opts.IndentString(""))
+	}
+	e := encoder.Encode(data)
 	if e != nil {
 		return nil, types.NewFormatError(e.Error(), "json")
 	}
-	return result, nil
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
 }
 
 func (j *JSONCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
@@ -27,11 +43,17 @@ func (j *JSONCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
 		return nil, nil
 	}
 	
+	// 数字保留为json.Number，避免超过2^53的整数和高精度小数丢失精度
 	var result interface{}
-	e := json.Unmarshal(data, &result)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	e := decoder.Decode(&result)
 	if e != nil {
 		return nil, types.NewFormatError(e.Error(), "json")
 	}
+	if _, e = decoder.Token(); e != io.EOF {
+		return nil, types.NewFormatError("json内容之后存在多余的字符", "json")
+	}
 	return result, nil
 }
 
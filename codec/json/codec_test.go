@@ -1,7 +1,9 @@
 package json
 
 import (
+	"encoding/json"
 	"fmt"
+	codec2 "github.com/izern/zf/codec"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -51,3 +53,66 @@ func TestYamlCodec_Marshal(t *testing.T) {
 	assert.NotNil(t, err)
 
 }
+
+func TestJSONCodec_MarshalWithOptions(t *testing.T) {
+	codec := &JSONCodec{}
+	data := map[string]interface{}{"a": []interface{}{1, 2}, "b": "<x>"}
+
+	marshal, err := codec.MarshalWithOptions(data, codec2.DefaultMarshalOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":[1,2],"b":"\u003cx\u003e"}`, string(marshal))
+
+	opts := codec2.DefaultMarshalOptions()
+	opts.Indent = 2
+	opts.EscapeHTML = false
+	marshal, err = codec.MarshalWithOptions(data, opts)
+	assert.Nil(t, err)
+	fmt.Println(string(marshal))
+	assert.Equal(t, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": \"<x>\"\n}", string(marshal))
+
+	opts.Tab = true
+	marshal, err = codec.MarshalWithOptions(data, opts)
+	assert.Nil(t, err)
+	assert.Contains(t, string(marshal), "\n\t\"a\"")
+}
+
+func TestCanonicalize(t *testing.T) {
+	// RFC 8785 3.2.2 中的示例
+	var data interface{}
+	err := json.Unmarshal([]byte(`{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`), &data)
+	assert.Nil(t, err)
+	canonical, zfErr := Canonicalize(data)
+	assert.Nil(t, zfErr)
+	assert.Equal(t, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, string(canonical))
+
+	// 键按UTF-16编码排序
+	canonical, zfErr = Canonicalize(map[interface{}]interface{}{"\U0001F600": 1, "דּ": 2, "a": int64(3), "<": -0.0})
+	assert.Nil(t, zfErr)
+	assert.Equal(t, "{\"<\":0,\"a\":3,\"\U0001F600\":1,\"דּ\":2}", string(canonical))
+
+	codec := &JSONCodec{}
+	opts := codec2.DefaultMarshalOptions()
+	opts.Canonical = true
+	marshal, zfErr := codec.MarshalWithOptions(map[string]interface{}{"b": "<x>", "a": 1.0}, opts)
+	assert.Nil(t, zfErr)
+	assert.Equal(t, `{"a":1,"b":"<x>"}`, string(marshal))
+}
+
+func TestJSONCodec_Numbers(t *testing.T) {
+	codec := &JSONCodec{}
+	data := `{"id":9007199254740993,"d":0.1000000000000000000001,"f":1.50}`
+	result, err := codec.Unmarshal([]byte(data))
+	assert.Nil(t, err)
+	assert.Equal(t, json.Number("9007199254740993"), result.(map[string]interface{})["id"])
+
+	marshal, err := codec.Marshal(result)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"d":0.1000000000000000000001,"f":1.50,"id":9007199254740993}`, string(marshal))
+
+	_, err = codec.Unmarshal([]byte(`[1] x`))
+	assert.NotNil(t, err)
+}
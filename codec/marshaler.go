@@ -1,12 +1,59 @@
 package codec
 
-import "github.com/izern/zf/types"
+import (
+	"strings"
+
+	"github.com/izern/zf/types"
+)
 
 // Marshaler defines the interface for encoding data to bytes
 type Marshaler interface {
 	// Marshal encodes the given data structure into bytes
 	// Returns the encoded bytes or an error if encoding fails
 	Marshal(data interface{}) ([]byte, types.ZfError)
+	// MarshalWithOptions encodes the given data structure using the formatting options
+	// Options the format does not support are ignored
+	MarshalWithOptions(data interface{}, opts MarshalOptions) ([]byte, types.ZfError)
+}
+
+// QuoteStyle defines how string values are quoted in formats that allow a choice
+type QuoteStyle string
+
+const (
+	DefaultQuote QuoteStyle = ""       // Let the encoder decide, usually unquoted when possible
+	SingleQuote  QuoteStyle = "single" // Always use single quotes
+	DoubleQuote  QuoteStyle = "double" // Always use double quotes
+)
+
+// MarshalOptions defines the formatting options passed to a Marshaler
+type MarshalOptions struct {
+	Indent     int        // Number of spaces per indentation level, 0 uses the format default
+	Tab        bool       // Indent with tabs instead of spaces
+	Compact    bool       // Produce the most compact output the format allows
+	SortKeys   bool       // Sort object keys, maps are always written in key order
+	EscapeHTML bool       // Escape <, > and & in JSON strings
+	FlowLevel  int        // YAML nesting level from which flow style is used, -1 disables it
+	QuoteStyle QuoteStyle // Quoting of YAML string values
+	Canonical  bool       // JSON Canonicalization Scheme (RFC 8785), overrides the other JSON options
+}
+
+// DefaultMarshalOptions returns the options used by Marshal
+func DefaultMarshalOptions() MarshalOptions {
+	return MarshalOptions{
+		EscapeHTML: true,
+		FlowLevel:  -1,
+	}
+}
+
+// IndentString returns the indentation unit, or def when neither Tab nor Indent is set
+func (o MarshalOptions) IndentString(def string) string {
+	if o.Tab {
+		return "\t"
+	}
+	if o.Indent > 0 {
+		return strings.Repeat(" ", o.Indent)
+	}
+	return def
 }
 
 // CodecCapabilities defines what features a codec supports
@@ -15,6 +62,7 @@ type CodecCapabilities struct {
 	SupportsArrays  bool // Can handle array/slice structures
 	SupportsPrimitives bool // Can handle primitive types (string, number, bool)
 	SupportsComments bool // Can handle comments in the format
+	IsBinary bool // Encoded form is binary rather than text
 }
 
 // CodecInfo provides metadata about a codec
@@ -0,0 +1,83 @@
+package msgpack
+
+import (
+	"bytes"
+
+	"github.com/izern/zf/codec"
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func init() {
+
+}
+
+type MsgpackCodec struct {
+}
+
+func (m *MsgpackCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
+	return m.MarshalWithOptions(data, codec.DefaultMarshalOptions())
+}
+
+// MarshalWithOptions 二进制格式没有排版，忽略格式选项，object总是按键排序输出
+func (m *MsgpackCodec) MarshalWithOptions(data interface{}, opts codec.MarshalOptions) ([]byte, types.ZfError) {
+	// msgpack没有任意精度的数字，无法精确表示的数字按字符串输出
+	data = util.MapNumbers(data, util.NumberOrString)
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	// 保证输出稳定，便于生成测试数据
+	enc.SetSortMapKeys(true)
+	e := enc.Encode(data)
+	if e != nil {
+		return nil, types.NewFormatError(e.Error(), "msgpack")
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *MsgpackCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	// map的key不一定是字符串，统一解析后再转换
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+	result, e := dec.DecodeInterface()
+	if e != nil {
+		return nil, types.NewFormatError(e.Error(), "msgpack")
+	}
+	return util.NormalizeValue(result), nil
+}
+
+func (m *MsgpackCodec) GetInfo() codec.CodecInfo {
+	return codec.CodecInfo{
+		Name:           "msgpack",
+		FileExtensions: []string{".msgpack", ".mpk"},
+		MimeTypes:      []string{"application/msgpack", "application/x-msgpack"},
+		Capabilities: codec.CodecCapabilities{
+			SupportsObjects:    true,
+			SupportsArrays:     true,
+			SupportsPrimitives: true,
+			SupportsComments:   false,
+			IsBinary:           true,
+		},
+	}
+}
+
+func (m *MsgpackCodec) CanHandle(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	// 顶层一般是map或array: fixmap, fixarray, array16/32, map16/32
+	first := data[0]
+	isContainer := (first >= 0x80 && first <= 0x9f) || (first >= 0xdc && first <= 0xdf)
+	if !isContainer {
+		return false
+	}
+	_, err := m.Unmarshal(data)
+	return err == nil
+}
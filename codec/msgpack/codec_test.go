@@ -0,0 +1,54 @@
+package msgpack
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_Unmarshal(t *testing.T) {
+	// {"a":"Easy","b":{"c":20000000,"d":[3,4]}}
+	var data = []byte{
+		0x82, 0xa1, 'a', 0xa4, 'E', 'a', 's', 'y',
+		0xa1, 'b', 0x82, 0xa1, 'c', 0xce, 0x01, 0x31, 0x2d, 0x00,
+		0xa1, 'd', 0x92, 0x03, 0x04,
+	}
+	codec := &MsgpackCodec{}
+	assert.True(t, codec.CanHandle(data))
+	result, err := codec.Unmarshal(data)
+	assert.Nil(t, err)
+	fmt.Println(result)
+	m, ok := result.(map[string]interface{})
+	assert.True(t, ok, "msgpack map should decode to map[string]interface{}")
+	assert.Equal(t, "Easy", m["a"])
+	assert.IsType(t, map[string]interface{}{}, m["b"])
+}
+
+func TestMsgpackCodec_Marshal(t *testing.T) {
+	codec := &MsgpackCodec{}
+
+	marshal, err := codec.Marshal(map[string]interface{}{
+		"a": "Easy",
+		"b": map[string]interface{}{
+			"c": 2000000,
+			"d": true},
+	})
+	assert.Nil(t, err)
+	res, err := codec.Unmarshal(marshal)
+	assert.Nil(t, err)
+	fmt.Println(res)
+
+	// 二进制字段转换为base64字符串
+	marshal, err = codec.Marshal(map[interface{}]interface{}{
+		1:   []byte("bin"),
+		"b": []interface{}{1, "x", nil},
+	})
+	assert.Nil(t, err)
+	res, err = codec.Unmarshal(marshal)
+	assert.Nil(t, err)
+	assert.Equal(t, "Ymlu", res.(map[string]interface{})["1"])
+}
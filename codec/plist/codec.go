@@ -0,0 +1,150 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/izern/zf/codec"
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+	"howett.net/plist"
+)
+
+func init() {
+
+}
+
+// PlistCodec Apple property list，读取时自动识别XML和二进制格式
+// Binary为true时输出二进制格式，否则输出XML格式
+type PlistCodec struct {
+	Binary bool
+}
+
+func (p *PlistCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
+	return p.MarshalWithOptions(data, codec.DefaultMarshalOptions())
+}
+
+// MarshalWithOptions XML格式默认使用tab缩进，Compact 时不换行缩进，二进制格式忽略格式选项
+func (p *PlistCodec) MarshalWithOptions(data interface{}, opts codec.MarshalOptions) ([]byte, types.ZfError) {
+	var result []byte
+	var e error
+	switch {
+	case p.Binary:
+		result, e = plist.Marshal(dropNulls(data), plist.BinaryFormat)
+	case opts.Compact:
+		result, e = plist.Marshal(dropNulls(data), plist.XMLFormat)
+	default:
+		result, e = plist.MarshalIndent(dropNulls(data), plist.XMLFormat, opts.IndentString("\t"))
+	}
+	if e != nil {
+		return nil, types.NewFormatError(e.Error(), "plist")
+	}
+	return result, nil
+}
+
+func (p *PlistCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var result interface{}
+	_, e := plist.Unmarshal(data, &result)
+	if e != nil {
+		return nil, types.NewFormatError(e.Error(), "plist")
+	}
+	return util.NormalizeValue(convertUID(result)), nil
+}
+
+// dropNulls plist没有null类型，输出时忽略值为null的键和元素
+// plist的整数和小数不支持任意精度，无法精确表示的数字按字符串输出
+func dropNulls(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if item != nil {
+				res[k] = dropNulls(item)
+			}
+		}
+		return res
+	case map[interface{}]interface{}:
+		return dropNulls(util.ConvertMap2String(val))
+	case []interface{}:
+		res := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			if item != nil {
+				res = append(res, dropNulls(item))
+			}
+		}
+		return res
+	case json.Number:
+		return util.NumberOrString(val)
+	default:
+		return v
+	}
+}
+
+// convertUID 将keyed archive中的UID转换为普通整数
+func convertUID(v interface{}) interface{} {
+	switch val := v.(type) {
+	case plist.UID:
+		return uint64(val)
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = convertUID(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = convertUID(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+func (p *PlistCodec) GetInfo() codec.CodecInfo {
+	if p.Binary {
+		return codec.CodecInfo{
+			Name:           "bplist",
+			FileExtensions: []string{".bplist"},
+			MimeTypes:      []string{"application/x-bplist"},
+			Capabilities: codec.CodecCapabilities{
+				SupportsObjects:    true,
+				SupportsArrays:     true,
+				SupportsPrimitives: true,
+				SupportsComments:   false,
+				IsBinary:           true,
+			},
+		}
+	}
+	return codec.CodecInfo{
+		Name:           "plist",
+		FileExtensions: []string{".plist"},
+		MimeTypes:      []string{"application/x-plist", "text/x-plist"},
+		Capabilities: codec.CodecCapabilities{
+			SupportsObjects:    true,
+			SupportsArrays:     true,
+			SupportsPrimitives: true,
+			SupportsComments:   true,
+		},
+	}
+}
+
+func (p *PlistCodec) CanHandle(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return true
+	}
+
+	content := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(content, "<?xml") && !strings.HasPrefix(content, "<!DOCTYPE") && !strings.HasPrefix(content, "<plist") {
+		return false
+	}
+	return strings.Contains(content, "<plist")
+}
@@ -0,0 +1,63 @@
+package plist
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+
+}
+
+func Test_Unmarshal(t *testing.T) {
+	var data = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleVersion</key>
+	<string>1.2.3</string>
+	<key>Built</key>
+	<date>2024-01-02T03:04:05Z</date>
+	<key>Icon</key>
+	<data>aGVsbG8=</data>
+	<key>Archs</key>
+	<array><string>arm64</string><integer>7</integer></array>
+</dict>
+</plist>
+`
+	codec := &PlistCodec{}
+	assert.True(t, codec.CanHandle([]byte(data)))
+	result, err := codec.Unmarshal([]byte(data))
+	assert.Nil(t, err)
+	fmt.Println(result)
+	m, ok := result.(map[string]interface{})
+	assert.True(t, ok, "plist dict should decode to map[string]interface{}")
+	assert.Equal(t, "1.2.3", m["CFBundleVersion"])
+	// date和data转换为可表示的字符串
+	assert.Equal(t, "2024-01-02T03:04:05Z", m["Built"])
+	assert.Equal(t, "aGVsbG8=", m["Icon"])
+}
+
+func TestPlistCodec_Marshal(t *testing.T) {
+	value := map[string]interface{}{
+		"a": "Easy",
+		"b": map[string]interface{}{
+			"c": 2000000,
+			"d": true,
+			"e": nil},
+	}
+
+	for _, codec := range []*PlistCodec{{}, {Binary: true}} {
+		marshal, err := codec.Marshal(value)
+		assert.Nil(t, err)
+		assert.True(t, codec.CanHandle(marshal))
+		res, err := codec.Unmarshal(marshal)
+		assert.Nil(t, err)
+		fmt.Println(res)
+		b := res.(map[string]interface{})["b"].(map[string]interface{})
+		assert.Equal(t, true, b["d"])
+		_, ok := b["e"]
+		assert.False(t, ok, "null should be dropped")
+	}
+}
@@ -1,9 +1,11 @@
 package toml
 
 import (
+	"bytes"
 	"encoding/json"
 	"github.com/izern/zf/codec"
 	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
 	"github.com/pelletier/go-toml/v2"
 	"strings"
 )
@@ -16,6 +18,11 @@ type TomlCodec struct {
 }
 
 func (t *TomlCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
+	return t.MarshalWithOptions(data, codec.DefaultMarshalOptions())
+}
+
+// MarshalWithOptions 指定缩进时子表按层级缩进，Compact 时子表以inline table输出
+func (t *TomlCodec) MarshalWithOptions(data interface{}, opts codec.MarshalOptions) ([]byte, types.ZfError) {
 	dataType, err := types.GetType(data)
 	if err != nil {
 		return nil, err
@@ -26,7 +33,17 @@ func (t *TomlCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
 	
 	switch dataType {
 	case types.Object:
-		result, e = toml.Marshal(data)
+		data = util.MapNumbers(data, toTomlNumber)
+		var buf bytes.Buffer
+		encoder := toml.NewEncoder(&buf)
+		if opts.Compact {
+			encoder.SetTablesInline(true)
+		} else if indent := opts.IndentString(""); indent != "" {
+			encoder.SetIndentSymbol(indent)
+			encoder.SetIndentTables(true)
+		}
+		e = encoder.Encode(data)
+		result = buf.Bytes()
 	default:
 		// TOML can't handle primitives at root level, use JSON fallback
 		result, e = json.Marshal(data)
@@ -38,6 +55,15 @@ func (t *TomlCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
 	return result, nil
 }
 
+// toTomlNumber toml的整数为64位有符号整数，小数为float64，超出范围或无法精确表示的数字按字符串输出
+func toTomlNumber(n json.Number) interface{} {
+	v, ok := util.ExactNumber(n)
+	if _, isUint := v.(uint64); !ok || isUint {
+		return string(n)
+	}
+	return v
+}
+
 func (t *TomlCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
 	if len(data) == 0 {
 		return nil, nil
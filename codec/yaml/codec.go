@@ -1,8 +1,12 @@
 package yaml
 
 import (
+	"bytes"
+	"encoding/json"
+
 	"github.com/izern/zf/codec"
 	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
 	"gopkg.in/yaml.v3"
 	"strings"
 )
@@ -15,11 +19,73 @@ type YamlCodec struct {
 }
 
 func (y *YamlCodec) Marshal(data interface{}) ([]byte, types.ZfError) {
-	result, e := yaml.Marshal(data)
+	return y.MarshalWithOptions(data, codec.DefaultMarshalOptions())
+}
+
+// MarshalWithOptions 默认缩进4个空格，yaml不允许使用tab缩进，忽略Tab选项
+// Compact 时整个文档使用flow风格输出在一行内
+func (y *YamlCodec) MarshalWithOptions(data interface{}, opts codec.MarshalOptions) ([]byte, types.ZfError) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	indent := 4
+	if opts.Indent > 0 {
+		indent = opts.Indent
+	}
+	encoder.SetIndent(indent)
+
+	data = util.MapNumbers(data, func(n json.Number) interface{} {
+		return number(n)
+	})
+
+	flowLevel := opts.FlowLevel
+	if opts.Compact {
+		flowLevel = 0
+	}
+	var e error
+	if flowLevel >= 0 || opts.QuoteStyle != codec.DefaultQuote {
+		var node yaml.Node
+		e = node.Encode(data)
+		if e == nil {
+			applyStyle(&node, 0, flowLevel, opts.QuoteStyle)
+			e = encoder.Encode(&node)
+		}
+	} else {
+		e = encoder.Encode(data)
+	}
+	if e == nil {
+		e = encoder.Close()
+	}
 	if e != nil {
 		return nil, types.NewFormatError(e.Error(), "yaml")
 	}
-	return result, nil
+	return buf.Bytes(), nil
+}
+
+// applyStyle 深度不小于flowLevel的object和array使用flow风格，字符串值按quoteStyle加引号
+func applyStyle(node *yaml.Node, depth int, flowLevel int, quoteStyle codec.QuoteStyle) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		if flowLevel >= 0 && depth >= flowLevel {
+			node.Style = yaml.FlowStyle
+		}
+		for i, child := range node.Content {
+			// object的键保持默认风格
+			if node.Kind == yaml.MappingNode && i%2 == 0 {
+				continue
+			}
+			applyStyle(child, depth+1, flowLevel, quoteStyle)
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" {
+			return
+		}
+		switch quoteStyle {
+		case codec.SingleQuote:
+			node.Style = yaml.SingleQuotedStyle
+		case codec.DoubleQuote:
+			node.Style = yaml.DoubleQuotedStyle
+		}
+	}
 }
 
 func (y *YamlCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
@@ -27,6 +93,16 @@ func (y *YamlCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
 		return nil, nil
 	}
 	
+	var doc yaml.Node
+	if e := yaml.Unmarshal(data, &doc); e == nil && markNumbers(&doc) {
+		// 存在数字时从标记后的节点解析，再还原为保留原文的json.Number
+		var result interface{}
+		if e = doc.Decode(&result); e != nil {
+			return nil, types.NewFormatError(e.Error(), "yaml")
+		}
+		return restoreNumbers(result), nil
+	}
+
 	var result interface{}
 	// Try to unmarshal as a map first
 	var tmp = make(map[string]interface{})
@@ -43,6 +119,66 @@ func (y *YamlCodec) Unmarshal(data []byte) (interface{}, types.ZfError) {
 	return result, nil
 }
 
+// number 按原文输出的数字，不加引号
+type number json.Number
+
+func (n number) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(n)}, nil
+}
+
+// numberMark 标记json格式的十进制数字，与json的UseNumber一样保留原文，如 1.10
+const numberMark = "\x00zf-number:"
+
+// markNumbers 将十进制数字改为带标记的字符串，返回是否存在数字
+// 0x1F、.inf 等yaml特有的写法不是json格式的数字，仍按yaml解析
+func markNumbers(node *yaml.Node) bool {
+	marked := false
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			marked = markNumbers(child) || marked
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			marked = markNumbers(node.Content[i]) || marked
+		}
+	case yaml.ScalarNode:
+		if node.Style != 0 || (node.ShortTag() != "!!int" && node.ShortTag() != "!!float") {
+			return false
+		}
+		text := strings.TrimPrefix(node.Value, "+")
+		if !util.IsNumber(text) {
+			return false
+		}
+		node.Tag = "!!str"
+		node.Value = numberMark + text
+		return true
+	}
+	return marked
+}
+
+func restoreNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if strings.HasPrefix(val, numberMark) {
+			return json.Number(strings.TrimPrefix(val, numberMark))
+		}
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = restoreNumbers(item)
+		}
+	case map[interface{}]interface{}:
+		for k, item := range val {
+			val[k] = restoreNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = restoreNumbers(item)
+		}
+	}
+	return v
+}
+
 func (y *YamlCodec) GetInfo() codec.CodecInfo {
 	return codec.CodecInfo{
 		Name:           "yaml",
@@ -1,7 +1,9 @@
 package yaml
 
 import (
+	"encoding/json"
 	"fmt"
+	codec2 "github.com/izern/zf/codec"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -54,3 +56,58 @@ func TestYamlCodec_Marshal(t *testing.T) {
 	fmt.Println(marshal)
 
 }
+
+func TestYamlCodec_MarshalWithOptions(t *testing.T) {
+	codec := &YamlCodec{}
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": []interface{}{1, 2}},
+		"s": "hi",
+	}
+
+	opts := codec2.DefaultMarshalOptions()
+	opts.Indent = 2
+	marshal, err := codec.MarshalWithOptions(data, opts)
+	assert.Nil(t, err)
+	fmt.Println(string(marshal))
+	assert.Equal(t, "a:\n  b:\n    - 1\n    - 2\ns: hi\n", string(marshal))
+
+	opts.FlowLevel = 1
+	opts.QuoteStyle = codec2.DoubleQuote
+	marshal, err = codec.MarshalWithOptions(data, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "a: {b: [1, 2]}\ns: \"hi\"\n", string(marshal))
+
+	opts = codec2.DefaultMarshalOptions()
+	opts.Compact = true
+	opts.QuoteStyle = codec2.SingleQuote
+	marshal, err = codec.MarshalWithOptions(data, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "{a: {b: [1, 2]}, s: 'hi'}\n", string(marshal))
+}
+
+func TestYamlCodec_Numbers(t *testing.T) {
+	codec := &YamlCodec{}
+	result, err := codec.Unmarshal([]byte("a: 123456789012345678901234567890\nb: &x 0.30000000000000000000001\nc: *x\nd: \"123\"\ne: 1.5\n"))
+	assert.Nil(t, err)
+	m := result.(map[string]interface{})
+	assert.Equal(t, json.Number("123456789012345678901234567890"), m["a"])
+	assert.Equal(t, json.Number("0.30000000000000000000001"), m["c"])
+	assert.Equal(t, "123", m["d"])
+	assert.Equal(t, json.Number("1.5"), m["e"])
+
+	marshal, err := codec.Marshal(m)
+	assert.Nil(t, err)
+	assert.Equal(t, "a: 123456789012345678901234567890\nb: 0.30000000000000000000001\nc: 0.30000000000000000000001\nd: \"123\"\ne: 1.5\n", string(marshal))
+
+	// 所有十进制数字都保留原文
+	text := "a: 1.10\nb: 9007199254740993\nc: -0.0\nd: 1e+2\ne: 0x1F\nf: .inf\n"
+	result, err = codec.Unmarshal([]byte(text))
+	assert.Nil(t, err)
+	m = result.(map[string]interface{})
+	assert.Equal(t, json.Number("1.10"), m["a"])
+	assert.Equal(t, json.Number("9007199254740993"), m["b"])
+	assert.Equal(t, 31, m["e"])
+	marshal, err = codec.Marshal(m)
+	assert.Nil(t, err)
+	assert.Equal(t, "a: 1.10\nb: 9007199254740993\nc: -0.0\nd: 1e+2\ne: 31\nf: .inf\n", string(marshal))
+}
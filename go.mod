@@ -1,10 +1,13 @@
 module github.com/izern/zf
 
 require (
+	github.com/fxamacker/cbor/v2 v2.4.0
 	github.com/pelletier/go-toml/v2 v2.0.2
 	github.com/spf13/cobra v1.1.3-0.20210616015213-9a432671fd84
 	github.com/stretchr/testify v1.7.2
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	gopkg.in/yaml.v3 v3.0.1
+	howett.net/plist v1.0.1
 )
 
 go 1.15
@@ -0,0 +1,875 @@
+package query
+
+import (
+	encjson "encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// builtin 内置函数，参数为未求值的表达式，由函数决定如何求值
+type builtin func(env *env, input interface{}, args []node) ([]interface{}, error)
+
+// builtins 内置函数，键为 名称/参数个数
+var builtins = map[string]builtin{
+	"empty/0":  func(env *env, input interface{}, args []node) ([]interface{}, error) { return nil, nil },
+	"error/0":  simple(func(v interface{}) (interface{}, error) { return nil, queryError(v) }),
+	"error/1":  withValues(func(v interface{}, a []interface{}) (interface{}, error) { return nil, queryError(a[0]) }),
+	"not/0":    simple(func(v interface{}) (interface{}, error) { return !truthy(v), nil }),
+	"type/0":   simple(func(v interface{}) (interface{}, error) { return typeName(v), nil }),
+	"length/0": simple(length),
+	"keys/0":   simple(keys),
+	"has/1":    withValues(has),
+	"contains/1": withValues(func(v interface{}, a []interface{}) (interface{}, error) {
+		return contains(v, a[0])
+	}),
+	"add/0":          simple(add),
+	"any/0":          simple(func(v interface{}) (interface{}, error) { return anyAll(v, true) }),
+	"all/0":          simple(func(v interface{}) (interface{}, error) { return anyAll(v, false) }),
+	"any/1":          anyAllBy(true),
+	"all/1":          anyAllBy(false),
+	"map/1":          mapArray,
+	"map_values/1":   mapValues,
+	"select/1":       selectFn,
+	"sort/0":         simple(sortArray),
+	"sort_by/1":      sortBy,
+	"group_by/1":     groupBy,
+	"unique/0":       simple(unique),
+	"unique_by/1":    uniqueBy,
+	"min/0":          simple(func(v interface{}) (interface{}, error) { return extreme(v, -1) }),
+	"max/0":          simple(func(v interface{}) (interface{}, error) { return extreme(v, 1) }),
+	"min_by/1":       extremeBy(-1),
+	"max_by/1":       extremeBy(1),
+	"reverse/0":      simple(reverse),
+	"flatten/0":      simple(func(v interface{}) (interface{}, error) { return flatten(v, math.MaxInt32) }),
+	"flatten/1":      withValues(flattenDepth),
+	"first/0":        simple(func(v interface{}) (interface{}, error) { return index(v, int64(0)) }),
+	"last/0":         simple(func(v interface{}) (interface{}, error) { return index(v, int64(-1)) }),
+	"first/1":        firstOf,
+	"range/1":        expand(withValues(func(v interface{}, a []interface{}) (interface{}, error) { return rangeOf(int64(0), a[0]) })),
+	"range/2":        expand(withValues(func(v interface{}, a []interface{}) (interface{}, error) { return rangeOf(a[0], a[1]) })),
+	"limit/2":        limit,
+	"to_entries/0":   simple(toEntries),
+	"from_entries/0": simple(fromEntries),
+	"with_entries/1": withEntries,
+	"tostring/0":     simple(func(v interface{}) (interface{}, error) { return toString(v) }),
+	"tojson/0":       simple(func(v interface{}) (interface{}, error) { return toJSON(v) }),
+	"tonumber/0":     simple(toNumber),
+	"fromjson/0":     simple(fromJSON),
+	"ascii_downcase/0": simple(func(v interface{}) (interface{}, error) {
+		return mapString("ascii_downcase", v, strings.ToLower)
+	}),
+	"ascii_upcase/0": simple(func(v interface{}) (interface{}, error) {
+		return mapString("ascii_upcase", v, strings.ToUpper)
+	}),
+	"split/1":      withValues(split),
+	"join/1":       withValues(join),
+	"startswith/1": withValues(stringTest("startswith", strings.HasPrefix)),
+	"endswith/1":   withValues(stringTest("endswith", strings.HasSuffix)),
+	"ltrimstr/1":   withValues(trimString(strings.TrimPrefix)),
+	"rtrimstr/1":   withValues(trimString(strings.TrimSuffix)),
+	"test/1":       withValues(test),
+	"test/2":       withValues(test),
+	"sub/2":        withValues(replace(false)),
+	"sub/3":        withValues(replace(false)),
+	"gsub/2":       withValues(replace(true)),
+	"gsub/3":       withValues(replace(true)),
+	"floor/0":      simple(mathFn("floor", math.Floor)),
+	"ceil/0":       simple(mathFn("ceil", math.Ceil)),
+	"round/0":      simple(mathFn("round", math.Round)),
+	"sqrt/0":       simple(mathFn("sqrt", math.Sqrt)),
+}
+
+// simple 没有参数的函数，每个输入产生一个输出
+func simple(fn func(v interface{}) (interface{}, error)) builtin {
+	return func(env *env, input interface{}, args []node) ([]interface{}, error) {
+		v, err := fn(input)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{v}, nil
+	}
+}
+
+// withValues 参数对输入求值后传给函数，参数有多个输出时按笛卡尔积逐一调用
+func withValues(fn func(v interface{}, args []interface{}) (interface{}, error)) builtin {
+	return func(env *env, input interface{}, args []node) ([]interface{}, error) {
+		combos := [][]interface{}{{}}
+		for _, arg := range args {
+			values, err := arg.eval(env, input)
+			if err != nil {
+				return nil, err
+			}
+			next := make([][]interface{}, 0, len(combos)*len(values))
+			for _, combo := range combos {
+				for _, v := range values {
+					item := make([]interface{}, len(combo), len(combo)+1)
+					copy(item, combo)
+					next = append(next, append(item, v))
+				}
+			}
+			combos = next
+		}
+		res := make([]interface{}, 0, len(combos))
+		for _, combo := range combos {
+			v, err := fn(input, combo)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, v)
+		}
+		return res, nil
+	}
+}
+
+// expand 将函数返回的每个数组展开为多个输出
+func expand(fn builtin) builtin {
+	return func(env *env, input interface{}, args []node) ([]interface{}, error) {
+		out, err := fn(env, input, args)
+		if err != nil {
+			return nil, err
+		}
+		var res []interface{}
+		for _, o := range out {
+			res = append(res, o.([]interface{})...)
+		}
+		return res, nil
+	}
+}
+
+// evalOne 对表达式求值，要求只有一个输出
+func evalOne(name string, n node, env *env, input interface{}) (interface{}, error) {
+	values, err := n.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("%s的参数应只有一个输出，实际为%d个", name, len(values))
+	}
+	return values[0], nil
+}
+
+// arrayInput 要求输入为数组
+func arrayInput(name string, v interface{}) ([]interface{}, error) {
+	array, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s的输入应为array，实际为%s", name, describe(v))
+	}
+	return array, nil
+}
+
+func queryError(v interface{}) error {
+	if s, ok := v.(string); ok {
+		return fmt.Errorf("%s", s)
+	}
+	return fmt.Errorf("%s", describe(v))
+}
+
+func length(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		return int64(0), nil
+	case bool:
+		return nil, fmt.Errorf("%s没有长度", describe(v))
+	case string:
+		return int64(len([]rune(val))), nil
+	case []interface{}:
+		return int64(len(val)), nil
+	case map[string]interface{}:
+		return int64(len(val)), nil
+	default:
+		// 数字的长度为绝对值
+		if _, ok := toFloat(v); ok {
+			if compareNumbers(v, int64(0)) < 0 {
+				return arithmetic("-", int64(0), v)
+			}
+			return v, nil
+		}
+		return nil, fmt.Errorf("%s没有长度", describe(v))
+	}
+}
+
+func keys(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make([]interface{}, 0, len(val))
+		for _, k := range sortedKeys(val) {
+			res = append(res, k)
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i := range val {
+			res[i] = int64(i)
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("%s没有keys", describe(v))
+	}
+}
+
+func has(v interface{}, args []interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if key, ok := args[0].(string); ok {
+			_, exists := val[key]
+			return exists, nil
+		}
+	case []interface{}:
+		if f, ok := toFloat(args[0]); ok {
+			return f >= 0 && f < float64(len(val)), nil
+		}
+	}
+	return nil, fmt.Errorf("无法判断%s是否包含键%s", describe(v), describe(args[0]))
+}
+
+// contains 字符串判断子串，数组判断每个元素都被包含，object递归判断
+func contains(a interface{}, b interface{}) (bool, error) {
+	if typeName(a) != typeName(b) {
+		return false, fmt.Errorf("%s和%s不能判断包含关系", describe(a), describe(b))
+	}
+	switch av := a.(type) {
+	case string:
+		return strings.Contains(av, b.(string)), nil
+	case []interface{}:
+		for _, bi := range b.([]interface{}) {
+			found := false
+			for _, ai := range av {
+				if typeName(ai) != typeName(bi) {
+					continue
+				}
+				ok, err := contains(ai, bi)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	case map[string]interface{}:
+		for k, bv := range b.(map[string]interface{}) {
+			ai, exists := av[k]
+			if !exists {
+				return false, nil
+			}
+			ok, err := contains(ai, bv)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	default:
+		return compare(a, b) == 0, nil
+	}
+}
+
+func add(v interface{}) (interface{}, error) {
+	values, err := iterate(v)
+	if err != nil {
+		return nil, fmt.Errorf("add的输入应为array，实际为%s", describe(v))
+	}
+	var res interface{}
+	for _, item := range values {
+		if res, err = arithmetic("+", res, item); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func anyAll(v interface{}, isAny bool) (interface{}, error) {
+	values, err := arrayInput(map[bool]string{true: "any", false: "all"}[isAny], v)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range values {
+		if truthy(item) == isAny {
+			return isAny, nil
+		}
+	}
+	return !isAny, nil
+}
+
+func anyAllBy(isAny bool) builtin {
+	return func(env *env, input interface{}, args []node) ([]interface{}, error) {
+		values, err := iterate(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range values {
+			out, err := args[0].eval(env, item)
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range out {
+				if truthy(o) == isAny {
+					return []interface{}{isAny}, nil
+				}
+			}
+		}
+		return []interface{}{!isAny}, nil
+	}
+}
+
+func mapArray(env *env, input interface{}, args []node) ([]interface{}, error) {
+	values, err := iterate(input)
+	if err != nil {
+		return nil, fmt.Errorf("map的输入应为array或object，实际为%s", describe(input))
+	}
+	res := make([]interface{}, 0, len(values))
+	for _, item := range values {
+		out, err := args[0].eval(env, item)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, out...)
+	}
+	return []interface{}{res}, nil
+}
+
+// mapValues 对数组元素或object的值求值，取第一个输出，没有输出时删除该项
+func mapValues(env *env, input interface{}, args []node) ([]interface{}, error) {
+	switch val := input.(type) {
+	case []interface{}:
+		res := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			out, err := args[0].eval(env, item)
+			if err != nil {
+				return nil, err
+			}
+			if len(out) > 0 {
+				res = append(res, out[0])
+			}
+		}
+		return []interface{}{res}, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out, err := args[0].eval(env, item)
+			if err != nil {
+				return nil, err
+			}
+			if len(out) > 0 {
+				res[k] = out[0]
+			}
+		}
+		return []interface{}{res}, nil
+	default:
+		return nil, fmt.Errorf("map_values的输入应为array或object，实际为%s", describe(input))
+	}
+}
+
+func selectFn(env *env, input interface{}, args []node) ([]interface{}, error) {
+	out, err := args[0].eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, o := range out {
+		if truthy(o) {
+			res = append(res, input)
+		}
+	}
+	return res, nil
+}
+
+func sortArray(v interface{}) (interface{}, error) {
+	values, err := arrayInput("sort", v)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]interface{}, len(values))
+	copy(res, values)
+	sortValues(res)
+	return res, nil
+}
+
+// keyed 数组元素和按表达式计算出的排序键，键为表达式所有输出组成的数组
+type keyed struct {
+	key   interface{}
+	value interface{}
+}
+
+func keyedValues(name string, env *env, input interface{}, n node) ([]keyed, error) {
+	values, err := arrayInput(name, input)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]keyed, len(values))
+	for i, item := range values {
+		out, err := n.eval(env, item)
+		if err != nil {
+			return nil, err
+		}
+		if out == nil {
+			out = []interface{}{}
+		}
+		res[i] = keyed{key: out, value: item}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return compare(res[i].key, res[j].key) < 0
+	})
+	return res, nil
+}
+
+func sortBy(env *env, input interface{}, args []node) ([]interface{}, error) {
+	items, err := keyedValues("sort_by", env, input, args[0])
+	if err != nil {
+		return nil, err
+	}
+	res := make([]interface{}, len(items))
+	for i, item := range items {
+		res[i] = item.value
+	}
+	return []interface{}{res}, nil
+}
+
+func groupBy(env *env, input interface{}, args []node) ([]interface{}, error) {
+	items, err := keyedValues("group_by", env, input, args[0])
+	if err != nil {
+		return nil, err
+	}
+	res := []interface{}{}
+	for i, item := range items {
+		if i == 0 || compare(items[i-1].key, item.key) != 0 {
+			res = append(res, []interface{}{})
+		}
+		last := len(res) - 1
+		res[last] = append(res[last].([]interface{}), item.value)
+	}
+	return []interface{}{res}, nil
+}
+
+func unique(v interface{}) (interface{}, error) {
+	sorted, err := sortArray(v)
+	if err != nil {
+		return nil, err
+	}
+	values := sorted.([]interface{})
+	res := []interface{}{}
+	for i, item := range values {
+		if i == 0 || compare(values[i-1], item) != 0 {
+			res = append(res, item)
+		}
+	}
+	return res, nil
+}
+
+func uniqueBy(env *env, input interface{}, args []node) ([]interface{}, error) {
+	items, err := keyedValues("unique_by", env, input, args[0])
+	if err != nil {
+		return nil, err
+	}
+	res := []interface{}{}
+	for i, item := range items {
+		if i == 0 || compare(items[i-1].key, item.key) != 0 {
+			res = append(res, item.value)
+		}
+	}
+	return []interface{}{res}, nil
+}
+
+// extreme sign为-1时返回最小值，为1时返回最大值，空数组返回null
+func extreme(v interface{}, sign int) (interface{}, error) {
+	values, err := arrayInput(map[int]string{-1: "min", 1: "max"}[sign], v)
+	if err != nil {
+		return nil, err
+	}
+	var res interface{}
+	for i, item := range values {
+		if i == 0 || compare(item, res)*sign >= 0 {
+			res = item
+		}
+	}
+	return res, nil
+}
+
+func extremeBy(sign int) builtin {
+	return func(env *env, input interface{}, args []node) ([]interface{}, error) {
+		items, err := keyedValues(map[int]string{-1: "min_by", 1: "max_by"}[sign], env, input, args[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return []interface{}{nil}, nil
+		}
+		if sign < 0 {
+			return []interface{}{items[0].value}, nil
+		}
+		return []interface{}{items[len(items)-1].value}, nil
+	}
+}
+
+func reverse(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		return []interface{}{}, nil
+	case string:
+		runes := []rune(val)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[len(val)-1-i] = item
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("无法反转%s", describe(v))
+	}
+}
+
+func flattenDepth(v interface{}, args []interface{}) (interface{}, error) {
+	depth, ok := toFloat(args[0])
+	if !ok || depth < 0 {
+		return nil, fmt.Errorf("flatten的深度应为非负数，实际为%s", describe(args[0]))
+	}
+	return flatten(v, int(depth))
+}
+
+func flatten(v interface{}, depth int) (interface{}, error) {
+	values, err := arrayInput("flatten", v)
+	if err != nil {
+		return nil, err
+	}
+	res := []interface{}{}
+	for _, item := range values {
+		if inner, ok := item.([]interface{}); ok && depth > 0 {
+			flat, _ := flatten(inner, depth-1)
+			res = append(res, flat.([]interface{})...)
+			continue
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func firstOf(env *env, input interface{}, args []node) ([]interface{}, error) {
+	out, err := args[0].eval(env, input)
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	return out[:1], nil
+}
+
+// rangeOf 返回[from, to)之间步长为1的数字，结果为数组，由range展开为多个输出
+func rangeOf(from interface{}, to interface{}) (interface{}, error) {
+	start, okFrom := toFloat(from)
+	end, okTo := toFloat(to)
+	if !okFrom || !okTo {
+		return nil, fmt.Errorf("range的参数应为数字，实际为%s和%s", describe(from), describe(to))
+	}
+	res := []interface{}{}
+	for n := start; n < end; n++ {
+		if n == math.Trunc(n) && math.Abs(n) < math.MaxInt64 {
+			res = append(res, int64(n))
+		} else {
+			res = append(res, n)
+		}
+	}
+	return res, nil
+}
+
+func limit(env *env, input interface{}, args []node) ([]interface{}, error) {
+	n, err := evalOne("limit", args[0], env, input)
+	if err != nil {
+		return nil, err
+	}
+	count, ok := toFloat(n)
+	if !ok {
+		return nil, fmt.Errorf("limit的数量应为数字，实际为%s", describe(n))
+	}
+	if count <= 0 {
+		return nil, nil
+	}
+	out, err := args[1].eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	if float64(len(out)) > count {
+		out = out[:int(count)]
+	}
+	return out, nil
+}
+
+func toEntries(v interface{}) (interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("to_entries的输入应为object，实际为%s", describe(v))
+	}
+	res := make([]interface{}, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		res = append(res, map[string]interface{}{"key": k, "value": m[k]})
+	}
+	return res, nil
+}
+
+// fromEntries 支持key、k、name、Name、Key、K作为键，value、v、Value、V作为值
+func fromEntries(v interface{}) (interface{}, error) {
+	values, err := arrayInput("from_entries", v)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]interface{}, len(values))
+	for _, item := range values {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("from_entries的元素应为object，实际为%s", describe(item))
+		}
+		var key interface{}
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if k, exists := entry[name]; exists && k != nil {
+				key = k
+				break
+			}
+		}
+		var value interface{}
+		for _, name := range []string{"value", "v", "Value", "V"} {
+			if val, exists := entry[name]; exists {
+				value = val
+				break
+			}
+		}
+		switch k := key.(type) {
+		case string:
+			res[k] = value
+		case bool:
+			res[strconv.FormatBool(k)] = value
+		default:
+			if _, ok := toFloat(k); !ok {
+				return nil, fmt.Errorf("from_entries的键应为字符串，实际为%s", describe(key))
+			}
+			text, _ := toJSON(k)
+			res[text] = value
+		}
+	}
+	return res, nil
+}
+
+func withEntries(env *env, input interface{}, args []node) ([]interface{}, error) {
+	entries, err := toEntries(input)
+	if err != nil {
+		return nil, fmt.Errorf("with_entries的输入应为object，实际为%s", describe(input))
+	}
+	mapped, err := mapArray(env, entries, args)
+	if err != nil {
+		return nil, err
+	}
+	v, err := fromEntries(mapped[0])
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{v}, nil
+}
+
+func toNumber(v interface{}) (interface{}, error) {
+	if _, ok := toFloat(v); ok {
+		return v, nil
+	}
+	s, ok := v.(string)
+	if !ok || !util.IsNumber(strings.TrimSpace(s)) {
+		return nil, fmt.Errorf("%s无法转换为数字", describe(v))
+	}
+	return util.ParseNumber(strings.TrimSpace(s)), nil
+}
+
+func fromJSON(v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("fromjson的输入应为字符串，实际为%s", describe(v))
+	}
+	res, err := (&json.JSONCodec{}).Unmarshal([]byte(s))
+	if err != nil {
+		return nil, fmt.Errorf("%s不是合法的json: %v", describe(v), err.Error())
+	}
+	return normalizeNumbers(res), nil
+}
+
+// normalizeNumbers 将json.Number转换为计算时使用的数字类型
+func normalizeNumbers(v interface{}) interface{} {
+	return util.MapNumbers(v, func(n encjson.Number) interface{} {
+		return util.ParseNumber(string(n))
+	})
+}
+
+func mapString(name string, v interface{}, fn func(string) string) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s的输入应为字符串，实际为%s", name, describe(v))
+	}
+	return fn(s), nil
+}
+
+// stringArgs 要求输入和参数都为字符串
+func stringArgs(name string, v interface{}, args []interface{}) (string, []string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", nil, fmt.Errorf("%s的输入应为字符串，实际为%s", name, describe(v))
+	}
+	res := make([]string, len(args))
+	for i, arg := range args {
+		if res[i], ok = arg.(string); !ok {
+			return "", nil, fmt.Errorf("%s的参数应为字符串，实际为%s", name, describe(arg))
+		}
+	}
+	return s, res, nil
+}
+
+func split(v interface{}, args []interface{}) (interface{}, error) {
+	s, a, err := stringArgs("split", v, args)
+	if err != nil {
+		return nil, err
+	}
+	return splitString(s, a[0]), nil
+}
+
+func join(v interface{}, args []interface{}) (interface{}, error) {
+	values, err := arrayInput("join", v)
+	if err != nil {
+		return nil, err
+	}
+	sep, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("join的参数应为字符串，实际为%s", describe(args[0]))
+	}
+	parts := make([]string, len(values))
+	for i, item := range values {
+		switch val := item.(type) {
+		case nil:
+		case string:
+			parts[i] = val
+		case bool:
+			parts[i] = strconv.FormatBool(val)
+		default:
+			if _, ok := toFloat(item); !ok {
+				return nil, fmt.Errorf("join无法连接%s", describe(item))
+			}
+			parts[i], _ = toJSON(item)
+		}
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func stringTest(name string, fn func(string, string) bool) func(interface{}, []interface{}) (interface{}, error) {
+	return func(v interface{}, args []interface{}) (interface{}, error) {
+		s, a, err := stringArgs(name, v, args)
+		if err != nil {
+			return nil, err
+		}
+		return fn(s, a[0]), nil
+	}
+}
+
+// trimString 输入或参数不是字符串时原样返回输入
+func trimString(fn func(string, string) string) func(interface{}, []interface{}) (interface{}, error) {
+	return func(v interface{}, args []interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		prefix, okPrefix := args[0].(string)
+		if !ok || !okPrefix {
+			return v, nil
+		}
+		return fn(s, prefix), nil
+	}
+}
+
+// compileRegex 编译正则，flags支持i(忽略大小写)、x(忽略空白)、s(.匹配换行)、g(全局替换)
+func compileRegex(name string, pattern string, flags string) (*regexp.Regexp, bool, error) {
+	global := false
+	var modes string
+	for _, f := range flags {
+		switch f {
+		case 'g':
+			global = true
+		case 'i', 's':
+			modes += string(f)
+		case 'x':
+			pattern = regexp.MustCompile(`\s+`).ReplaceAllString(pattern, "")
+		default:
+			return nil, false, fmt.Errorf("%s不支持的正则标志%c", name, f)
+		}
+	}
+	if modes != "" {
+		pattern = "(?" + modes + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s的正则格式错误: %v", name, err)
+	}
+	return re, global, nil
+}
+
+func test(v interface{}, args []interface{}) (interface{}, error) {
+	s, a, err := stringArgs("test", v, args)
+	if err != nil {
+		return nil, err
+	}
+	flags := ""
+	if len(a) > 1 {
+		flags = a[1]
+	}
+	re, _, err := compileRegex("test", a[0], flags)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(s), nil
+}
+
+// replace 正则替换，替换内容中可以用$1、${name}引用分组
+func replace(global bool) func(interface{}, []interface{}) (interface{}, error) {
+	name := map[bool]string{false: "sub", true: "gsub"}[global]
+	return func(v interface{}, args []interface{}) (interface{}, error) {
+		s, a, err := stringArgs(name, v, args)
+		if err != nil {
+			return nil, err
+		}
+		flags := ""
+		if len(a) > 2 {
+			flags = a[2]
+		}
+		re, g, err := compileRegex(name, a[0], flags)
+		if err != nil {
+			return nil, err
+		}
+		if global || g {
+			return re.ReplaceAllString(s, a[1]), nil
+		}
+		loc := re.FindStringSubmatchIndex(s)
+		if loc == nil {
+			return s, nil
+		}
+		dst := re.ExpandString(nil, a[1], s, loc)
+		return s[:loc[0]] + string(dst) + s[loc[1]:], nil
+	}
+}
+
+func mathFn(name string, fn func(float64) float64) func(interface{}) (interface{}, error) {
+	return func(v interface{}) (interface{}, error) {
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("%s的输入应为数字，实际为%s", name, describe(v))
+		}
+		res := fn(f)
+		if res == math.Trunc(res) && math.Abs(res) < math.MaxInt64 {
+			return int64(res), nil
+		}
+		return res, nil
+	}
+}
@@ -0,0 +1,650 @@
+package query
+
+import (
+	"fmt"
+	"math"
+)
+
+func init() {
+
+}
+
+// node 表达式的语法树节点，对一个输入可以产生零个或多个输出
+type node interface {
+	eval(env *env, input interface{}) ([]interface{}, error)
+}
+
+// env 变量作用域
+type env struct {
+	name   string
+	value  interface{}
+	parent *env
+}
+
+func (e *env) bind(name string, value interface{}) *env {
+	return &env{name: name, value: value, parent: e}
+}
+
+func (e *env) lookup(name string) (interface{}, bool) {
+	for cur := e; cur != nil; cur = cur.parent {
+		if cur.name == name {
+			return cur.value, true
+		}
+	}
+	return nil, false
+}
+
+// identityNode .
+type identityNode struct{}
+
+func (n identityNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	return []interface{}{input}, nil
+}
+
+// recurseNode .. 输出自身和所有子孙节点
+type recurseNode struct{}
+
+func (n recurseNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	var res []interface{}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		res = append(res, v)
+		switch val := v.(type) {
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, k := range sortedKeys(val) {
+				walk(val[k])
+			}
+		}
+	}
+	walk(input)
+	return res, nil
+}
+
+// literalNode 常量
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	return []interface{}{n.value}, nil
+}
+
+// stringPartNode 字符串插值中的一段，node不为空时为表达式
+type stringPartNode struct {
+	text string
+	node node
+}
+
+// stringNode 包含插值的字符串，如 "\(.name):\(.port)"
+type stringNode struct {
+	parts []stringPartNode
+}
+
+func (n stringNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	results := []string{""}
+	for _, part := range n.parts {
+		if part.node == nil {
+			for i := range results {
+				results[i] += part.text
+			}
+			continue
+		}
+		values, err := part.node.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		next := make([]string, 0, len(results)*len(values))
+		for _, prefix := range results {
+			for _, v := range values {
+				s, err := toString(v)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, prefix+s)
+			}
+		}
+		results = next
+	}
+	res := make([]interface{}, len(results))
+	for i, s := range results {
+		res[i] = s
+	}
+	return res, nil
+}
+
+// varNode $name
+type varNode struct {
+	name string
+}
+
+func (n varNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	v, ok := env.lookup(n.name)
+	if !ok {
+		return nil, fmt.Errorf("变量$%s未定义", n.name)
+	}
+	return []interface{}{v}, nil
+}
+
+// indexNode target[index]，index对原始输入求值
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n indexNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := n.index.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, t := range targets {
+		for _, i := range indexes {
+			v, err := index(t, i)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, v)
+		}
+	}
+	return res, nil
+}
+
+// index 按键或下标取值，不存在时返回null，负数下标从末尾开始
+func index(v interface{}, i interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		switch i.(type) {
+		case string, nil:
+			return nil, nil
+		}
+		if _, ok := toFloat(i); ok {
+			return nil, nil
+		}
+	case map[string]interface{}:
+		if key, ok := i.(string); ok {
+			return val[key], nil
+		}
+	case []interface{}:
+		if f, ok := toFloat(i); ok {
+			pos := int(math.Floor(f))
+			if pos < 0 {
+				pos += len(val)
+			}
+			if pos < 0 || pos >= len(val) {
+				return nil, nil
+			}
+			return val[pos], nil
+		}
+	}
+	return nil, fmt.Errorf("无法用%s索引%s", describe(i), describe(v))
+}
+
+// sliceNode target[from:to]
+type sliceNode struct {
+	target node
+	from   node
+	to     node
+}
+
+func (n sliceNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	froms := []interface{}{nil}
+	if n.from != nil {
+		if froms, err = n.from.eval(env, input); err != nil {
+			return nil, err
+		}
+	}
+	tos := []interface{}{nil}
+	if n.to != nil {
+		if tos, err = n.to.eval(env, input); err != nil {
+			return nil, err
+		}
+	}
+	var res []interface{}
+	for _, t := range targets {
+		for _, from := range froms {
+			for _, to := range tos {
+				v, err := slice(t, from, to)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, v)
+			}
+		}
+	}
+	return res, nil
+}
+
+func slice(v interface{}, from interface{}, to interface{}) (interface{}, error) {
+	var length int
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		length = len(val)
+	case string:
+		length = len([]rune(val))
+	default:
+		return nil, fmt.Errorf("无法对%s切片", describe(v))
+	}
+	bound := func(b interface{}, def int) (int, error) {
+		if b == nil {
+			return def, nil
+		}
+		f, ok := toFloat(b)
+		if !ok {
+			return 0, fmt.Errorf("切片的下标必须是数字，实际为%s", describe(b))
+		}
+		pos := int(math.Floor(f))
+		if pos < 0 {
+			pos += length
+		}
+		if pos < 0 {
+			pos = 0
+		}
+		if pos > length {
+			pos = length
+		}
+		return pos, nil
+	}
+	start, err := bound(from, 0)
+	if err != nil {
+		return nil, err
+	}
+	end, err := bound(to, length)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		end = start
+	}
+	if s, ok := v.(string); ok {
+		return string([]rune(s)[start:end]), nil
+	}
+	array := v.([]interface{})
+	res := make([]interface{}, end-start)
+	copy(res, array[start:end])
+	return res, nil
+}
+
+// iterateNode target[]，输出数组的元素或object的值
+type iterateNode struct {
+	target node
+}
+
+func (n iterateNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, t := range targets {
+		values, err := iterate(t)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, values...)
+	}
+	return res, nil
+}
+
+// iterate 返回数组的元素，或按键排序的object的值
+func iterate(v interface{}) ([]interface{}, error) {
+	switch val := v.(type) {
+	case []interface{}:
+		return val, nil
+	case map[string]interface{}:
+		res := make([]interface{}, 0, len(val))
+		for _, k := range sortedKeys(val) {
+			res = append(res, val[k])
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("无法遍历%s", describe(v))
+	}
+}
+
+// optionalNode expr? 忽略错误
+type optionalNode struct {
+	inner node
+}
+
+func (n optionalNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	res, err := n.inner.eval(env, input)
+	if err != nil {
+		return nil, nil
+	}
+	return res, nil
+}
+
+// pipeNode left | right
+type pipeNode struct {
+	left  node
+	right node
+}
+
+func (n pipeNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	values, err := n.left.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, v := range values {
+		out, err := n.right.eval(env, v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, out...)
+	}
+	return res, nil
+}
+
+// commaNode left, right 依次输出两边的结果
+type commaNode struct {
+	left  node
+	right node
+}
+
+func (n commaNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// arrayNode [expr]，收集所有输出为数组
+type arrayNode struct {
+	inner node
+}
+
+func (n arrayNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	if n.inner == nil {
+		return []interface{}{[]interface{}{}}, nil
+	}
+	values, err := n.inner.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = []interface{}{}
+	}
+	return []interface{}{values}, nil
+}
+
+type objectEntry struct {
+	key   node
+	value node
+}
+
+// objectNode {key: value, ...}，键或值有多个输出时输出所有组合
+type objectNode struct {
+	entries []objectEntry
+}
+
+func (n objectNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	results := []map[string]interface{}{{}}
+	for _, entry := range n.entries {
+		keys, err := entry.key.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		values, err := entry.value.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		next := make([]map[string]interface{}, 0, len(results)*len(keys)*len(values))
+		for _, obj := range results {
+			for _, k := range keys {
+				key, ok := k.(string)
+				if !ok {
+					return nil, fmt.Errorf("object的键必须是字符串，实际为%s", describe(k))
+				}
+				for _, v := range values {
+					copied := make(map[string]interface{}, len(obj)+1)
+					for ck, cv := range obj {
+						copied[ck] = cv
+					}
+					copied[key] = v
+					next = append(next, copied)
+				}
+			}
+		}
+		results = next
+	}
+	res := make([]interface{}, len(results))
+	for i, obj := range results {
+		res[i] = obj
+	}
+	return res, nil
+}
+
+// binaryNode 算术和比较运算，两边有多个输出时输出所有组合
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n binaryNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	rights, err := n.right.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	lefts, err := n.left.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, r := range rights {
+		for _, l := range lefts {
+			v, err := binary(n.op, l, r)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, v)
+		}
+	}
+	return res, nil
+}
+
+func binary(op string, l interface{}, r interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return compare(l, r) == 0, nil
+	case "!=":
+		return compare(l, r) != 0, nil
+	case "<":
+		return compare(l, r) < 0, nil
+	case "<=":
+		return compare(l, r) <= 0, nil
+	case ">":
+		return compare(l, r) > 0, nil
+	case ">=":
+		return compare(l, r) >= 0, nil
+	default:
+		return arithmetic(op, l, r)
+	}
+}
+
+// logicNode and、or，短路求值
+type logicNode struct {
+	and   bool
+	left  node
+	right node
+}
+
+func (n logicNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, l := range lefts {
+		if truthy(l) != n.and {
+			// and的左边为假或or的左边为真时不再计算右边
+			res = append(res, !n.and)
+			continue
+		}
+		rights, err := n.right.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rights {
+			res = append(res, truthy(r))
+		}
+	}
+	return res, nil
+}
+
+// alternativeNode left // right，left没有为真的输出时使用right
+type alternativeNode struct {
+	left  node
+	right node
+}
+
+func (n alternativeNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(env, input)
+	var res []interface{}
+	if err == nil {
+		for _, l := range lefts {
+			if truthy(l) {
+				res = append(res, l)
+			}
+		}
+	}
+	if len(res) > 0 {
+		return res, nil
+	}
+	return n.right.eval(env, input)
+}
+
+// negNode -expr
+type negNode struct {
+	inner node
+}
+
+func (n negNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	values, err := n.inner.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		if res[i], err = arithmetic("-", int64(0), v); err != nil {
+			return nil, fmt.Errorf("无法对%s取负", describe(v))
+		}
+	}
+	return res, nil
+}
+
+// ifNode if cond then a else b end，elif转换为嵌套的if
+type ifNode struct {
+	cond      node
+	then      node
+	otherwise node
+}
+
+func (n ifNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	conds, err := n.cond.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, c := range conds {
+		branch := n.otherwise
+		if truthy(c) {
+			branch = n.then
+		}
+		out, err := branch.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, out...)
+	}
+	return res, nil
+}
+
+// asNode source as $name | body
+type asNode struct {
+	source node
+	name   string
+	body   node
+}
+
+func (n asNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	values, err := n.source.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, v := range values {
+		out, err := n.body.eval(env.bind(n.name, v), input)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, out...)
+	}
+	return res, nil
+}
+
+// reduceNode reduce source as $name (init; update)
+type reduceNode struct {
+	source node
+	name   string
+	init   node
+	update node
+}
+
+func (n reduceNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	values, err := n.source.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	inits, err := n.init.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, acc := range inits {
+		for _, v := range values {
+			out, err := n.update.eval(env.bind(n.name, v), acc)
+			if err != nil {
+				return nil, err
+			}
+			if len(out) == 0 {
+				acc = nil
+				continue
+			}
+			acc = out[len(out)-1]
+		}
+		res = append(res, acc)
+	}
+	return res, nil
+}
+
+// callNode 调用内置函数
+type callNode struct {
+	name string
+	args []node
+	fn   builtin
+}
+
+func (n callNode) eval(env *env, input interface{}) ([]interface{}, error) {
+	return n.fn(env, input, n.args)
+}
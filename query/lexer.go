@@ -0,0 +1,218 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+
+}
+
+type tokenKind uint8
+
+const (
+	_         tokenKind = iota
+	tokEOF              // 结束
+	tokPunct            // 运算符和标点，如 | , . [ ]
+	tokField            // .name
+	tokIdent            // 函数名和关键字
+	tokVar              // $name
+	tokNumber           // 数字
+	tokString           // 字符串，可能包含 \(...) 插值
+)
+
+// stringPart 字符串中的一段，expr不为空时为插值表达式
+type stringPart struct {
+	text string
+	expr string
+}
+
+type token struct {
+	kind  tokenKind
+	text  string
+	parts []stringPart
+	pos   int
+}
+
+// 按长度从长到短匹配的运算符
+var punctuations = []string{"..", "==", "!=", "<=", ">=", "//", "|", ",", ".", "[", "]", "{", "}", "(", ")", ":", ";", "+", "-", "*", "/", "%", "<", ">", "?"}
+
+// lex 将表达式拆分为token
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(expr) {
+		r, size := utf8.DecodeRuneInString(expr[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '#':
+			// 注释到行尾
+			for i < len(expr) && expr[i] != '\n' {
+				i++
+			}
+		case r == '"':
+			parts, end, err := lexString(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, parts: parts, text: expr[i:end], pos: i})
+			i = end
+		case r >= '0' && r <= '9':
+			end := lexNumber(expr, i)
+			tokens = append(tokens, token{kind: tokNumber, text: expr[i:end], pos: i})
+			i = end
+		case r == '.' && i+1 < len(expr) && isIdentStart(rune(expr[i+1])):
+			end := lexIdent(expr, i+1)
+			tokens = append(tokens, token{kind: tokField, text: expr[i+1 : end], pos: i})
+			i = end
+		case r == '$' && i+1 < len(expr) && isIdentStart(rune(expr[i+1])):
+			end := lexIdent(expr, i+1)
+			tokens = append(tokens, token{kind: tokVar, text: expr[i+1 : end], pos: i})
+			i = end
+		case isIdentStart(r):
+			end := lexIdent(expr, i)
+			tokens = append(tokens, token{kind: tokIdent, text: expr[i:end], pos: i})
+			i = end
+		default:
+			matched := false
+			for _, p := range punctuations {
+				if strings.HasPrefix(expr[i:], p) {
+					tokens = append(tokens, token{kind: tokPunct, text: p, pos: i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("位置%d无法识别的字符%q", i, r)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func lexIdent(expr string, i int) int {
+	for i < len(expr) {
+		c := expr[i]
+		if !(isIdentStart(rune(c)) || (c >= '0' && c <= '9')) {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+func lexNumber(expr string, i int) int {
+	for i < len(expr) && (expr[i] >= '0' && expr[i] <= '9' || expr[i] == '.') {
+		i++
+	}
+	if i < len(expr) && (expr[i] == 'e' || expr[i] == 'E') {
+		j := i + 1
+		if j < len(expr) && (expr[j] == '+' || expr[j] == '-') {
+			j++
+		}
+		if j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+			i = j
+			for i < len(expr) && expr[i] >= '0' && expr[i] <= '9' {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+// lexString 解析从start开始的字符串，返回各段内容和结束位置
+func lexString(expr string, start int) ([]stringPart, int, error) {
+	var parts []stringPart
+	var sb strings.Builder
+	i := start + 1
+	for i < len(expr) {
+		c := expr[i]
+		switch c {
+		case '"':
+			if sb.Len() > 0 || len(parts) == 0 {
+				parts = append(parts, stringPart{text: sb.String()})
+			}
+			return parts, i + 1, nil
+		case '\\':
+			if i+1 >= len(expr) {
+				return nil, 0, fmt.Errorf("位置%d的字符串没有结束", start)
+			}
+			i++
+			switch expr[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case '"', '\\', '/':
+				sb.WriteByte(expr[i])
+			case 'u':
+				if i+4 >= len(expr) {
+					return nil, 0, fmt.Errorf("位置%d的\\u转义格式错误", i)
+				}
+				code, err := strconv.ParseUint(expr[i+1:i+5], 16, 32)
+				if err != nil {
+					return nil, 0, fmt.Errorf("位置%d的\\u转义格式错误", i)
+				}
+				sb.WriteRune(rune(code))
+				i += 4
+			case '(':
+				end, err := matchParen(expr, i)
+				if err != nil {
+					return nil, 0, err
+				}
+				if sb.Len() > 0 {
+					parts = append(parts, stringPart{text: sb.String()})
+					sb.Reset()
+				}
+				parts = append(parts, stringPart{expr: expr[i+1 : end]})
+				i = end
+			default:
+				return nil, 0, fmt.Errorf("位置%d不支持的转义\\%c", i, expr[i])
+			}
+			i++
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return nil, 0, fmt.Errorf("位置%d的字符串没有结束", start)
+}
+
+// matchParen 返回与open处的括号匹配的右括号位置，跳过其中的字符串
+func matchParen(expr string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(expr); i++ {
+		switch expr[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		case '"':
+			_, end, err := lexString(expr, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		}
+	}
+	return 0, fmt.Errorf("位置%d的插值没有结束", open)
+}
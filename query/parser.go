@@ -0,0 +1,574 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// parser 递归下降解析，优先级从低到高为
+// | , // or and 比较 +- */% 一元负号 后缀(.key [..] ?)
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(expr string) (node, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.unexpected(t)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept 下一个token为指定的运算符或关键字时跳过并返回true
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokPunct || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		if t.kind == tokEOF {
+			return fmt.Errorf("表达式不完整，缺少%s", text)
+		}
+		return fmt.Errorf("位置%d应为%s，实际为%s", t.pos, text, tokenText(t))
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokEOF {
+		return fmt.Errorf("表达式不完整")
+	}
+	return fmt.Errorf("位置%d无法识别%s", t.pos, tokenText(t))
+}
+
+func tokenText(t token) string {
+	switch t.kind {
+	case tokField:
+		return "." + t.text
+	case tokVar:
+		return "$" + t.text
+	default:
+		return t.text
+	}
+}
+
+func (p *parser) parsePipe() (node, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("|") {
+		right, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return pipeNode{left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseComma() (node, error) {
+	left, err := p.parseAlternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(",") {
+		right, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+		left = commaNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAlternative() (node, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("//") {
+		right, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+		return alternativeNode{left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if p.peek().kind != tokPunct || (op != "+" && op != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if p.peek().kind != tokPunct || (op != "*" && op != "/" && op != "%") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("-") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{inner: inner}, nil
+	}
+	return p.parsePostfix(true)
+}
+
+// parsePostfix 解析基本项和其后的 .key、[...]、?，allowAs为true时支持 term as $x | body
+func (p *parser) parsePostfix(allowAs bool) (node, error) {
+	term, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokField:
+			p.next()
+			term = indexNode{target: term, index: literalNode{value: t.text}}
+		case t.kind == tokPunct && t.text == "." && p.tokens[p.pos+1].kind == tokString:
+			p.next()
+			key, err := p.parseString(p.next())
+			if err != nil {
+				return nil, err
+			}
+			term = indexNode{target: term, index: key}
+		case t.kind == tokPunct && t.text == "." && p.tokens[p.pos+1].text == "[" && p.tokens[p.pos+1].kind == tokPunct:
+			p.next()
+		case t.kind == tokPunct && t.text == "[":
+			p.next()
+			if term, err = p.parseBracket(term); err != nil {
+				return nil, err
+			}
+		case t.kind == tokPunct && t.text == "?":
+			p.next()
+			term = optionalNode{inner: term}
+		case allowAs && t.kind == tokIdent && t.text == "as":
+			p.next()
+			name := p.next()
+			if name.kind != tokVar {
+				return nil, fmt.Errorf("位置%d的as后应为变量，如 as $x", name.pos)
+			}
+			if err = p.expect("|"); err != nil {
+				return nil, err
+			}
+			body, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			return asNode{source: term, name: name.text, body: body}, nil
+		default:
+			return term, nil
+		}
+	}
+}
+
+// parseBracket 解析 [] [expr] [from:to]，左括号已经读取
+func (p *parser) parseBracket(target node) (node, error) {
+	if p.accept("]") {
+		return iterateNode{target: target}, nil
+	}
+	if p.accept(":") {
+		to, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+		return sliceNode{target: target, to: to}, nil
+	}
+	from, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept(":") {
+		var to node
+		if !p.accept("]") {
+			if to, err = p.parsePipe(); err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+		}
+		return sliceNode{target: target, from: from, to: to}, nil
+	}
+	if err = p.expect("]"); err != nil {
+		return nil, err
+	}
+	return indexNode{target: target, index: from}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokField:
+		return indexNode{target: identityNode{}, index: literalNode{value: t.text}}, nil
+	case tokNumber:
+		if !util.IsNumber(t.text) {
+			return nil, fmt.Errorf("位置%d的数字格式错误: %s", t.pos, t.text)
+		}
+		return literalNode{value: util.ParseNumber(t.text)}, nil
+	case tokString:
+		return p.parseString(t)
+	case tokVar:
+		return varNode{name: t.text}, nil
+	case tokIdent:
+		return p.parseIdent(t)
+	case tokPunct:
+		switch t.text {
+		case ".":
+			if p.peek().kind == tokString {
+				key, err := p.parseString(p.next())
+				if err != nil {
+					return nil, err
+				}
+				return indexNode{target: identityNode{}, index: key}, nil
+			}
+			return identityNode{}, nil
+		case "..":
+			return recurseNode{}, nil
+		case "(":
+			inner, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			if p.accept("]") {
+				return arrayNode{}, nil
+			}
+			inner, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+			return arrayNode{inner: inner}, nil
+		case "{":
+			return p.parseObject()
+		}
+	}
+	return nil, p.unexpected(t)
+}
+
+// parseString 解析字符串，插值部分递归解析为表达式
+func (p *parser) parseString(t token) (node, error) {
+	if len(t.parts) == 1 && t.parts[0].expr == "" {
+		return literalNode{value: t.parts[0].text}, nil
+	}
+	parts := make([]stringPartNode, len(t.parts))
+	for i, part := range t.parts {
+		if part.expr == "" {
+			parts[i] = stringPartNode{text: part.text}
+			continue
+		}
+		n, err := parse(part.expr)
+		if err != nil {
+			return nil, fmt.Errorf("字符串插值 \\(%s) 格式错误: %v", part.expr, err)
+		}
+		parts[i] = stringPartNode{node: n}
+	}
+	return stringNode{parts: parts}, nil
+}
+
+func (p *parser) parseIdent(t token) (node, error) {
+	switch t.text {
+	case "true":
+		return literalNode{value: true}, nil
+	case "false":
+		return literalNode{value: false}, nil
+	case "null":
+		return literalNode{value: nil}, nil
+	case "if":
+		return p.parseIf()
+	case "reduce":
+		return p.parseReduce()
+	case "then", "elif", "else", "end", "as", "and", "or":
+		return nil, p.unexpected(t)
+	}
+	var args []node
+	if p.accept("(") {
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err = p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	fn, ok := builtins[fmt.Sprintf("%s/%d", t.text, len(args))]
+	if !ok {
+		return nil, fmt.Errorf("位置%d的函数%s/%d不存在", t.pos, t.text, len(args))
+	}
+	return callNode{name: t.text, args: args, fn: fn}, nil
+}
+
+// parseIf 解析 if cond then a (elif cond then b)* (else c)? end，if已经读取
+func (p *parser) parseIf() (node, error) {
+	cond, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("elif") {
+		otherwise, err := p.parseIf()
+		if err != nil {
+			return nil, err
+		}
+		return ifNode{cond: cond, then: then, otherwise: otherwise}, nil
+	}
+	var otherwise node = identityNode{}
+	if p.accept("else") {
+		if otherwise, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.expect("end"); err != nil {
+		return nil, err
+	}
+	return ifNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// parseReduce 解析 reduce source as $x (init; update)，reduce已经读取
+func (p *parser) parseReduce() (node, error) {
+	source, err := p.parsePostfix(false)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("as"); err != nil {
+		return nil, err
+	}
+	name := p.next()
+	if name.kind != tokVar {
+		return nil, fmt.Errorf("位置%d的as后应为变量，如 as $x", name.pos)
+	}
+	if err = p.expect("("); err != nil {
+		return nil, err
+	}
+	init, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(";"); err != nil {
+		return nil, err
+	}
+	update, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	return reduceNode{source: source, name: name.text, init: init, update: update}, nil
+}
+
+// parseObject 解析 {a: 1, "b": .x, (.k): .v, c, $d}，左括号已经读取
+func (p *parser) parseObject() (node, error) {
+	var entries []objectEntry
+	if p.accept("}") {
+		return objectNode{}, nil
+	}
+	for {
+		t := p.next()
+		var key node
+		var shorthand node
+		switch t.kind {
+		case tokIdent:
+			key = literalNode{value: t.text}
+			shorthand = indexNode{target: identityNode{}, index: key}
+		case tokVar:
+			key = literalNode{value: t.text}
+			shorthand = varNode{name: t.text}
+		case tokNumber:
+			key = literalNode{value: t.text}
+		case tokString:
+			k, err := p.parseString(t)
+			if err != nil {
+				return nil, err
+			}
+			key = k
+			shorthand = indexNode{target: identityNode{}, index: key}
+		case tokPunct:
+			if t.text != "(" {
+				return nil, p.unexpected(t)
+			}
+			k, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			key = k
+		default:
+			return nil, p.unexpected(t)
+		}
+
+		var value node
+		if p.accept(":") {
+			v, err := p.parseObjectValue()
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		} else if shorthand != nil {
+			value = shorthand
+		} else {
+			return nil, fmt.Errorf("位置%d的键%s缺少值", t.pos, strings.TrimSpace(tokenText(t)))
+		}
+		entries = append(entries, objectEntry{key: key, value: value})
+
+		if p.accept("}") {
+			return objectNode{entries: entries}, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseObjectValue object的值不包含逗号，可以使用管道，如 {a: .b | length}
+func (p *parser) parseObjectValue() (node, error) {
+	value, err := p.parseAlternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		right, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+		value = pipeNode{left: value, right: right}
+	}
+	return value, nil
+}
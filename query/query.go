@@ -0,0 +1,35 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/izern/zf/types"
+)
+
+func init() {
+
+}
+
+// Query 编译后的query表达式，语法为jq的子集
+type Query struct {
+	expr string
+	root node
+}
+
+// Compile 解析query表达式，如 .proxies | map(select(.port > 1000)) | sort_by(.name)
+func Compile(expr string) (*Query, types.ZfError) {
+	root, err := parse(expr)
+	if err != nil {
+		return nil, types.NewFormatError(fmt.Sprintf("%s: %v", expr, err), "query表达式")
+	}
+	return &Query{expr: expr, root: root}, nil
+}
+
+// Run 对输入执行表达式，返回所有输出
+func (q *Query) Run(input interface{}) ([]interface{}, types.ZfError) {
+	res, err := q.root.eval(nil, normalizeNumbers(input))
+	if err != nil {
+		return nil, types.NewQueryError(q.expr, err.Error())
+	}
+	return res, nil
+}
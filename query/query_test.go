@@ -0,0 +1,150 @@
+package query
+
+import (
+	"fmt"
+	"github.com/izern/zf/codec/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func init() {
+
+}
+
+const queryDoc = `{
+  "port": 7890,
+  "name": "clash",
+  "big": 18446744073709551616,
+  "proxies": [
+    {"name": "hk-1", "type": "ss", "port": 443, "tags": ["hk", "fast"]},
+    {"name": "us-1", "type": "vmess", "port": 8443, "tags": ["us"]},
+    {"name": "hk-2", "type": "ss", "port": 80, "tags": []}
+  ]
+}`
+
+// runQuery 执行表达式，每个输出转换为一行紧凑的json
+func runQuery(t *testing.T, expr string) (string, error) {
+	doc, err := (&json.JSONCodec{}).Unmarshal([]byte(queryDoc))
+	assert.Nil(t, err)
+	q, zfErr := Compile(expr)
+	if zfErr != nil {
+		return "", zfErr.Error()
+	}
+	res, zfErr := q.Run(doc)
+	if zfErr != nil {
+		return "", zfErr.Error()
+	}
+	lines := make([]string, len(res))
+	for i, v := range res {
+		text, err := toJSON(v)
+		assert.Nil(t, err)
+		lines[i] = text
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func Test_Query(t *testing.T) {
+	cases := map[string]string{
+		`.`:                     `{"big":18446744073709551616,"name":"clash","port":7890,"proxies":[{"name":"hk-1","port":443,"tags":["hk","fast"],"type":"ss"},{"name":"us-1","port":8443,"tags":["us"],"type":"vmess"},{"name":"hk-2","port":80,"tags":[],"type":"ss"}]}`,
+		`.port`:                 `7890`,
+		`.missing`:              `null`,
+		`.proxies[0].name`:      `"hk-1"`,
+		`.proxies[-1].port`:     `80`,
+		`.proxies[].name`:       "\"hk-1\"\n\"us-1\"\n\"hk-2\"",
+		`.proxies[1:].[0].name`: `"us-1"`,
+		`.name[1:3]`:            `"la"`,
+		`.proxies | length`:     `3`,
+		`keys`:                  `["big","name","port","proxies"]`,
+		`.proxies | map(.port)`: `[443,8443,80]`,
+		`.proxies | map(select(.port > 100)) | map(.name)`:                   `["hk-1","us-1"]`,
+		`[.proxies[] | select(.type == "ss") | .name]`:                       `["hk-1","hk-2"]`,
+		`.proxies | sort_by(.port) | map(.name)`:                             `["hk-2","hk-1","us-1"]`,
+		`.proxies | group_by(.type) | map({type: .[0].type, count: length})`: `[{"count":2,"type":"ss"},{"count":1,"type":"vmess"}]`,
+		`.proxies | map(.port) | add`:                                        `8966`,
+		`.proxies | map(.port) | max`:                                        `8443`,
+		`.proxies | min_by(.port) | .name`:                                   `"hk-2"`,
+		`.port * 2 + 1`:                                                      `15781`,
+		`.port / 2`:                                                          `3945`,
+		`.port / 4`:                                                          `1972.5`,
+		`.port % 7`:                                                          `1`,
+		`-.port`:                                                             `-7890`,
+		`.big + 1`:                                                           `18446744073709552000`,
+		`9223372036854775807 + 1`:                                            `9223372036854776000`,
+		`"\(.name):\(.port)"`:                                                `"clash:7890"`,
+		`.name | ascii_upcase`:                                               `"CLASH"`,
+		`.proxies[0].name | split("-")`:                                      `["hk","1"]`,
+		`.proxies | map(.name) | join(",")`:                                  `"hk-1,us-1,hk-2"`,
+		`.proxies | map(select(.name | startswith("hk"))) | length`:              `2`,
+		`.proxies[0].name | test("HK"; "i")`:                                     `true`,
+		`.proxies[0].name | gsub("[0-9]"; "x")`:                                  `"hk-x"`,
+		`.proxies[0].name | sub("(?<r>[a-z]+)"; "${r}!")`:                        `"hk!-1"`,
+		`{name, p: .port, (.name): 1}`:                                           `{"clash":1,"name":"clash","p":7890}`,
+		`{a: (1, 2)}`:                                                            "{\"a\":1}\n{\"a\":2}",
+		`[.proxies[].tags[]] | unique`:                                           `["fast","hk","us"]`,
+		`[.proxies[] | .tags | length]`:                                          `[2,1,0]`,
+		`.proxies[0] | to_entries | map(.key)`:                                   `["name","port","tags","type"]`,
+		`.proxies[0] | with_entries(select(.key != "tags"))`:                     `{"name":"hk-1","port":443,"type":"ss"}`,
+		`.proxies[0] | has("tags"), has("x")`:                                    "true\nfalse",
+		`.proxies[0].tags | contains(["hk"])`:                                    `true`,
+		`if .port > 1000 then "high" elif .port > 100 then "mid" else "low" end`: `"high"`,
+		`.proxies | map(if .port < 100 then .name end) | .[2]`:                   `"hk-2"`,
+		`.missing // "default"`:                                                  `"default"`,
+		`.port and .missing, .port or .missing`:                                  "false\ntrue",
+		`.missing | not`:                                                         `true`,
+		`reduce .proxies[] as $p (0; . + $p.port)`:                               `8966`,
+		`.port as $p | .proxies | map(select(.port < $p)) | length`:              `2`,
+		`[range(3)], [range(1; 3)]`:                                              "[0,1,2]\n[1,2]",
+		`[limit(2; .proxies[].name)]`:                                            `["hk-1","us-1"]`,
+		`first(.proxies[].name)`:                                                 `"hk-1"`,
+		`[[1, [2]], 3] | flatten, flatten(1)`:                                    "[1,2,3]\n[1,[2],3]",
+		`.proxies | map(.port | tostring)`:                                       `["443","8443","80"]`,
+		`"12" | tonumber + 1`:                                                    `13`,
+		`{a: 1} * {b: {c: 2}} | tojson | fromjson`:                               `{"a":1,"b":{"c":2}}`,
+		`[.proxies[] | .type] - ["ss"]`:                                          `["vmess"]`,
+		`[.[] | numbers?] | length`:                                              ``,
+		`[..] | length`:                                                          `23`,
+		`[1, null, "a", true, [], {}, false] | sort`:                             `[null,false,true,1,"a",[],{}]`,
+		`[.proxies[].port | . > 100] | any, all`:                                 "true\nfalse",
+		`.port | type`:                                                           `"number"`,
+		`[.[] | type]`:                                                           `["number","string","number","array"]`,
+		`.port | sqrt | floor`:                                                   `88`,
+		`[.proxies[] | .tags[0]?] | map(. // "none")`:                            `["hk","us","none"]`,
+		`.proxies | map_values(.port)`:                                           `[443,8443,80]`,
+		`[.proxies[] | .name | ltrimstr("hk-")]`:                                 `["1","us-1","2"]`,
+		`. as {a: $x} | 1`:                                                       ``,
+		`# 注释
+.port`: `7890`,
+	}
+	for expr, expected := range cases {
+		actual, err := runQuery(t, expr)
+		if expected == "" {
+			fmt.Println(err)
+			assert.NotNil(t, err, expr)
+			continue
+		}
+		assert.Nil(t, err, expr)
+		assert.Equal(t, expected, actual, expr)
+	}
+}
+
+func Test_QueryError(t *testing.T) {
+	cases := map[string]string{
+		`.port | keys`: "query .port | keys 执行失败: number (7890)没有keys",
+		`.name + 1`:    `query .name + 1 执行失败: string ("clash")和number (1)不能进行+运算`,
+		`.port / 0`:    "query .port / 0 执行失败: number (7890)和number (0)不能相除，除数为0",
+		`error("bad")`: "query error(\"bad\") 执行失败: bad",
+		`$x`:           "query $x 执行失败: 变量$x未定义",
+		`.proxies[`:    "无法将解析化为query表达式格式, .proxies[: 表达式不完整",
+		`foo(1)`:       "无法将解析化为query表达式格式, foo(1): 位置0的函数foo/1不存在",
+		`if . then 1`:  "无法将解析化为query表达式格式, if . then 1: 表达式不完整，缺少end",
+		`.a ]`:         "无法将解析化为query表达式格式, .a ]: 位置3无法识别]",
+		`"abc`:         "无法将解析化为query表达式格式, \"abc: 位置0的字符串没有结束",
+	}
+	for expr, expected := range cases {
+		_, err := runQuery(t, expr)
+		if assert.NotNil(t, err, expr) {
+			assert.Equal(t, expected, err.Error(), expr)
+		}
+	}
+}
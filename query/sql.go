@@ -0,0 +1,588 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// SQL 编译后的sql语句，对指定路径下的object数组查询
+// SELECT 列 FROM 路径 [WHERE 条件] [GROUP BY 表达式] [HAVING 条件] [ORDER BY 表达式 [ASC|DESC]] [LIMIT n [OFFSET m]]
+type SQL struct {
+	text    string
+	From    string
+	items   []selectItem
+	where   sqlExpr
+	groupBy []sqlExpr
+	having  sqlExpr
+	orderBy []orderItem
+	limit   int
+	offset  int
+}
+
+// selectItem SELECT中的一列，star为true时表示 *
+type selectItem struct {
+	expr sqlExpr
+	name string
+	star bool
+}
+
+type orderItem struct {
+	expr sqlExpr
+	desc bool
+}
+
+// Columns 返回输出的列名，包含 * 时返回nil
+func (s *SQL) Columns() []string {
+	columns := make([]string, 0, len(s.items))
+	for _, item := range s.items {
+		if item.star {
+			return nil
+		}
+		columns = append(columns, item.name)
+	}
+	return columns
+}
+
+type sqlTokenKind uint8
+
+const (
+	_         sqlTokenKind = iota
+	sqlEOF                 // 结束
+	sqlPunct               // 运算符和标点
+	sqlIdent               // 列名、函数名和关键字，a.b 为一个token
+	sqlQuoted              // 使用 "name" 或 `name` 引起来的列名，不作为关键字
+	sqlNumber              // 数字
+	sqlString              // 'text'
+	sqlPath                // FROM 后的路径，如 .proxies
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	pos  int
+}
+
+// 按长度从长到短匹配的运算符
+var sqlPunctuations = []string{"<>", "!=", "<=", ">=", "=", "<", ">", "(", ")", ",", "*", "+", "-", "/", "%"}
+
+func lexSQL(text string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	i := 0
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '\'':
+			s, end, err := lexSQLQuoted(text, i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{kind: sqlString, text: s, pos: i})
+			i = end
+		case r == '"' || r == '`':
+			s, end, err := lexSQLQuoted(text, i, byte(r))
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{kind: sqlQuoted, text: s, pos: i})
+			i = end
+		case r >= '0' && r <= '9':
+			end := lexNumber(text, i)
+			tokens = append(tokens, sqlToken{kind: sqlNumber, text: text[i:end], pos: i})
+			i = end
+		case r == '.':
+			// 路径到空白为止
+			end := strings.IndexFunc(text[i:], unicode.IsSpace)
+			if end < 0 {
+				end = len(text) - i
+			}
+			tokens = append(tokens, sqlToken{kind: sqlPath, text: text[i : i+end], pos: i})
+			i += end
+		case isIdentStart(r):
+			end := lexIdent(text, i)
+			for end+1 < len(text) && text[end] == '.' && isIdentStart(rune(text[end+1])) {
+				end = lexIdent(text, end+1)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlIdent, text: text[i:end], pos: i})
+			i = end
+		default:
+			matched := false
+			for _, p := range sqlPunctuations {
+				if strings.HasPrefix(text[i:], p) {
+					tokens = append(tokens, sqlToken{kind: sqlPunct, text: p, pos: i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("位置%d无法识别的字符%q", i, r)
+			}
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlEOF, pos: len(text)}), nil
+}
+
+// lexSQLQuoted 解析引号包围的内容，连续两个引号表示引号本身
+func lexSQLQuoted(text string, start int, quote byte) (string, int, error) {
+	var sb strings.Builder
+	for i := start + 1; i < len(text); i++ {
+		if text[i] != quote {
+			sb.WriteByte(text[i])
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == quote {
+			sb.WriteByte(quote)
+			i++
+			continue
+		}
+		return sb.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("位置%d的%c没有结束", start, quote)
+}
+
+// sqlParser 递归下降解析，优先级从低到高为 OR AND NOT 比较 +- */% 一元负号
+type sqlParser struct {
+	text   string
+	tokens []sqlToken
+	pos    int
+}
+
+// CompileSQL 解析sql语句，如 SELECT name, port FROM .proxies WHERE type = 'ss' ORDER BY port
+func CompileSQL(text string) (*SQL, types.ZfError) {
+	s, err := parseSQL(text)
+	if err != nil {
+		return nil, types.NewFormatError(fmt.Sprintf("%s: %v", text, err), "sql语句")
+	}
+	return s, nil
+}
+
+func parseSQL(text string) (*SQL, error) {
+	tokens, err := lexSQL(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{text: text, tokens: tokens}
+	s := &SQL{text: text, limit: -1}
+	if err = p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	if s.items, err = p.parseSelectItems(); err != nil {
+		return nil, err
+	}
+	if err = p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	from := p.next()
+	if from.kind != sqlPath {
+		return nil, fmt.Errorf("位置%d的FROM后应为路径，如 .proxies", from.pos)
+	}
+	s.From = from.text
+	if p.acceptKeyword("WHERE") {
+		if s.where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("GROUP") {
+		if err = p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			s.groupBy = append(s.groupBy, e)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("HAVING") {
+		if s.having, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err = p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			item := orderItem{expr: e}
+			if p.acceptKeyword("DESC") {
+				item.desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			s.orderBy = append(s.orderBy, item)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		if s.limit, err = p.parseCount("LIMIT"); err != nil {
+			return nil, err
+		}
+		if p.acceptKeyword("OFFSET") {
+			if s.offset, err = p.parseCount("OFFSET"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if t := p.peek(); t.kind != sqlEOF {
+		return nil, p.unexpected(t)
+	}
+	if err = s.checkAggregate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	t := p.tokens[p.pos]
+	if t.kind != sqlEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *sqlParser) isKeyword(t sqlToken, keyword string) bool {
+	return t.kind == sqlIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (p *sqlParser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(p.peek(), keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) acceptPunct(punct string) bool {
+	t := p.peek()
+	if t.kind == sqlPunct && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect 跳过关键字或标点，不存在时报错
+func (p *sqlParser) expect(text string) error {
+	if p.acceptKeyword(text) || p.acceptPunct(text) {
+		return nil
+	}
+	t := p.peek()
+	if t.kind == sqlEOF {
+		return fmt.Errorf("语句不完整，缺少%s", text)
+	}
+	return fmt.Errorf("位置%d应为%s，实际为%s", t.pos, text, t.text)
+}
+
+func (p *sqlParser) unexpected(t sqlToken) error {
+	if t.kind == sqlEOF {
+		return fmt.Errorf("语句不完整")
+	}
+	return fmt.Errorf("位置%d无法识别%s", t.pos, t.text)
+}
+
+func (p *sqlParser) parseCount(keyword string) (int, error) {
+	t := p.next()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != sqlNumber || err != nil || n < 0 {
+		return 0, fmt.Errorf("位置%d的%s后应为非负整数", t.pos, keyword)
+	}
+	return n, nil
+}
+
+// 不能作为列别名的关键字
+var sqlClauses = []string{"FROM", "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET"}
+
+func (p *sqlParser) parseSelectItems() ([]selectItem, error) {
+	var items []selectItem
+	for {
+		if p.acceptPunct("*") {
+			items = append(items, selectItem{star: true})
+		} else {
+			start := p.peek().pos
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			item := selectItem{expr: e, name: strings.TrimSpace(p.text[start:p.peek().pos])}
+			if col, ok := e.(sqlColumn); ok {
+				item.name = col.name
+			}
+			if p.acceptKeyword("AS") {
+				alias := p.next()
+				if alias.kind != sqlIdent && alias.kind != sqlQuoted {
+					return nil, fmt.Errorf("位置%d的AS后应为列名", alias.pos)
+				}
+				item.name = alias.text
+			} else if t := p.peek(); t.kind == sqlQuoted || (t.kind == sqlIdent && !p.isClause(t)) {
+				p.next()
+				item.name = t.text
+			}
+			items = append(items, item)
+		}
+		if !p.acceptPunct(",") {
+			return items, nil
+		}
+	}
+}
+
+func (p *sqlParser) isClause(t sqlToken) bool {
+	for _, clause := range sqlClauses {
+		if p.isKeyword(t, clause) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *sqlParser) parseExpr() (sqlExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = sqlLogic{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAnd() (sqlExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = sqlLogic{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseNot() (sqlExpr, error) {
+	if p.acceptKeyword("NOT") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return sqlNot{inner: inner}, nil
+	}
+	return p.parseCompare()
+}
+
+// parseCompare 解析比较运算和 IS [NOT] NULL、[NOT] IN (...)、[NOT] LIKE
+func (p *sqlParser) parseCompare() (sqlExpr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"=", "!=", "<>", "<=", ">=", "<", ">"} {
+		if p.acceptPunct(op) {
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			if op == "<>" {
+				op = "!="
+			}
+			return sqlBinary{op: op, left: left, right: right}, nil
+		}
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if err = p.expect("NULL"); err != nil {
+			return nil, err
+		}
+		return sqlIsNull{inner: left, not: not}, nil
+	}
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("IN"):
+		if err = p.expect("("); err != nil {
+			return nil, err
+		}
+		var list []sqlExpr
+		for {
+			e, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, e)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return sqlIn{inner: left, list: list, not: not}, nil
+	case p.acceptKeyword("LIKE"):
+		pattern, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return sqlLike{inner: left, pattern: pattern, not: not}, nil
+	case not:
+		return nil, fmt.Errorf("位置%d的NOT后应为IN或LIKE", p.peek().pos)
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAdditive() (sqlExpr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != sqlPunct || (t.text != "+" && t.text != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = sqlBinary{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *sqlParser) parseMultiplicative() (sqlExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != sqlPunct || (t.text != "*" && t.text != "/" && t.text != "%") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = sqlBinary{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *sqlParser) parseUnary() (sqlExpr, error) {
+	if p.acceptPunct("-") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return sqlBinary{op: "-", left: sqlLiteral{value: int64(0)}, right: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *sqlParser) parsePrimary() (sqlExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case sqlNumber:
+		if !util.IsNumber(t.text) {
+			return nil, fmt.Errorf("位置%d的数字格式错误: %s", t.pos, t.text)
+		}
+		return sqlLiteral{value: util.ParseNumber(t.text)}, nil
+	case sqlString:
+		return sqlLiteral{value: t.text}, nil
+	case sqlQuoted:
+		return sqlColumn{name: t.text, path: []string{t.text}}, nil
+	case sqlPunct:
+		if t.text == "(" {
+			inner, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		}
+	case sqlIdent:
+		switch strings.ToUpper(t.text) {
+		case "NULL":
+			return sqlLiteral{value: nil}, nil
+		case "TRUE":
+			return sqlLiteral{value: true}, nil
+		case "FALSE":
+			return sqlLiteral{value: false}, nil
+		}
+		if p.isClause(t) {
+			return nil, p.unexpected(t)
+		}
+		if p.acceptPunct("(") {
+			return p.parseCall(t)
+		}
+		return sqlColumn{name: t.text, path: strings.Split(t.text, ".")}, nil
+	}
+	return nil, p.unexpected(t)
+}
+
+// parseCall 解析函数调用，左括号已经读取
+func (p *sqlParser) parseCall(name sqlToken) (sqlExpr, error) {
+	call := sqlCall{name: strings.ToUpper(name.text)}
+	arity, ok := sqlFunctions[call.name]
+	if !ok {
+		return nil, fmt.Errorf("位置%d的函数%s不存在", name.pos, name.text)
+	}
+	if call.name == "COUNT" && p.acceptPunct("*") {
+		call.star = true
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	} else if !p.acceptPunct(")") {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if !call.star && arity >= 0 && len(call.args) != arity {
+		return nil, fmt.Errorf("位置%d的函数%s需要%d个参数，实际为%d个", name.pos, call.name, arity, len(call.args))
+	}
+	if arity < 0 && len(call.args) == 0 {
+		return nil, fmt.Errorf("位置%d的函数%s至少需要1个参数", name.pos, call.name)
+	}
+	return call, nil
+}
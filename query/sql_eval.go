@@ -0,0 +1,651 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/izern/zf/types"
+)
+
+func init() {
+
+}
+
+// sqlContext 表达式求值的上下文
+// 分组时group为组内所有行，row为组内第一行；HAVING和ORDER BY时out为已经计算出的输出行，可以使用列别名
+type sqlContext struct {
+	row   interface{}
+	group []interface{}
+	out   map[string]interface{}
+}
+
+// sqlExpr sql表达式，null参与比较和运算时结果为null
+type sqlExpr interface {
+	eval(ctx *sqlContext) (interface{}, error)
+}
+
+// sqlColumn 列，a.b 表示嵌套的键
+type sqlColumn struct {
+	name string
+	path []string
+}
+
+func (e sqlColumn) eval(ctx *sqlContext) (interface{}, error) {
+	if ctx.out != nil {
+		if v, ok := ctx.out[e.name]; ok {
+			return v, nil
+		}
+	}
+	v := ctx.row
+	for _, key := range e.path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		v = m[key]
+	}
+	return v, nil
+}
+
+type sqlLiteral struct {
+	value interface{}
+}
+
+func (e sqlLiteral) eval(ctx *sqlContext) (interface{}, error) {
+	return e.value, nil
+}
+
+// sqlBinary 比较和算术运算
+type sqlBinary struct {
+	op    string
+	left  sqlExpr
+	right sqlExpr
+}
+
+func (e sqlBinary) eval(ctx *sqlContext) (interface{}, error) {
+	l, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	if e.op == "=" {
+		return compare(l, r) == 0, nil
+	}
+	return binary(e.op, l, r)
+}
+
+// sqlLogic AND、OR，按三值逻辑计算
+type sqlLogic struct {
+	and   bool
+	left  sqlExpr
+	right sqlExpr
+}
+
+func (e sqlLogic) eval(ctx *sqlContext) (interface{}, error) {
+	l, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// AND的左边为假或OR的左边为真时不再计算右边
+	if l != nil && truthy(l) != e.and {
+		return !e.and, nil
+	}
+	r, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil && truthy(r) != e.and {
+		return !e.and, nil
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	return e.and, nil
+}
+
+type sqlNot struct {
+	inner sqlExpr
+}
+
+func (e sqlNot) eval(ctx *sqlContext) (interface{}, error) {
+	v, err := e.inner.eval(ctx)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+// sqlIsNull IS [NOT] NULL，不存在的键也为null
+type sqlIsNull struct {
+	inner sqlExpr
+	not   bool
+}
+
+func (e sqlIsNull) eval(ctx *sqlContext) (interface{}, error) {
+	v, err := e.inner.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != e.not, nil
+}
+
+// sqlIn [NOT] IN (a, b, ...)
+type sqlIn struct {
+	inner sqlExpr
+	list  []sqlExpr
+	not   bool
+}
+
+func (e sqlIn) eval(ctx *sqlContext) (interface{}, error) {
+	v, err := e.inner.eval(ctx)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	for _, item := range e.list {
+		candidate, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if candidate != nil && compare(v, candidate) == 0 {
+			return !e.not, nil
+		}
+	}
+	return e.not, nil
+}
+
+// sqlLike [NOT] LIKE，%匹配任意个字符，_匹配一个字符，不区分大小写
+type sqlLike struct {
+	inner   sqlExpr
+	pattern sqlExpr
+	not     bool
+}
+
+func (e sqlLike) eval(ctx *sqlContext) (interface{}, error) {
+	v, err := e.inner.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := e.pattern.eval(ctx)
+	if err != nil || v == nil || p == nil {
+		return nil, err
+	}
+	s, err := toString(v)
+	if err != nil {
+		return nil, err
+	}
+	pattern, ok := p.(string)
+	if !ok {
+		return nil, fmt.Errorf("LIKE的模式应为字符串，实际为%s", describe(p))
+	}
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String()).MatchString(s) != e.not, nil
+}
+
+// sqlFunctions 支持的函数和参数个数，-1表示任意个
+var sqlFunctions = map[string]int{
+	"COUNT":    1,
+	"SUM":      1,
+	"AVG":      1,
+	"MIN":      1,
+	"MAX":      1,
+	"LOWER":    1,
+	"UPPER":    1,
+	"LENGTH":   1,
+	"COALESCE": -1,
+}
+
+// sqlCall 函数调用，COUNT、SUM、AVG、MIN、MAX为聚合函数
+type sqlCall struct {
+	name string
+	args []sqlExpr
+	star bool
+}
+
+func (e sqlCall) aggregate() bool {
+	switch e.name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		return true
+	default:
+		return false
+	}
+}
+
+func (e sqlCall) eval(ctx *sqlContext) (interface{}, error) {
+	if e.aggregate() {
+		return e.evalAggregate(ctx)
+	}
+	args := make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	switch e.name {
+	case "COALESCE":
+		for _, v := range args {
+			if v != nil {
+				return v, nil
+			}
+		}
+		return nil, nil
+	case "LENGTH":
+		if args[0] == nil {
+			return nil, nil
+		}
+		return length(args[0])
+	default:
+		s, ok := args[0].(string)
+		if !ok {
+			if args[0] == nil {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("%s的参数应为字符串，实际为%s", e.name, describe(args[0]))
+		}
+		if e.name == "LOWER" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+	}
+}
+
+// evalAggregate 对组内所有行计算聚合函数，忽略null
+func (e sqlCall) evalAggregate(ctx *sqlContext) (interface{}, error) {
+	if ctx.group == nil {
+		return nil, fmt.Errorf("聚合函数%s不能用于WHERE", e.name)
+	}
+	if e.star {
+		return int64(len(ctx.group)), nil
+	}
+	var values []interface{}
+	for _, row := range ctx.group {
+		v, err := e.args[0].eval(&sqlContext{row: row})
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			values = append(values, v)
+		}
+	}
+	switch e.name {
+	case "COUNT":
+		return int64(len(values)), nil
+	case "MIN", "MAX":
+		sign := map[string]int{"MIN": -1, "MAX": 1}[e.name]
+		return extreme(values, sign)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var sum interface{} = int64(0)
+	for _, v := range values {
+		if _, ok := toFloat(v); !ok {
+			return nil, fmt.Errorf("%s的参数应为数字，实际为%s", e.name, describe(v))
+		}
+		var err error
+		if sum, err = arithmetic("+", sum, v); err != nil {
+			return nil, err
+		}
+	}
+	if e.name == "SUM" {
+		return sum, nil
+	}
+	total, _ := toFloat(sum)
+	return total / float64(len(values)), nil
+}
+
+// hasAggregate 表达式中是否包含聚合函数
+func hasAggregate(e sqlExpr) bool {
+	switch val := e.(type) {
+	case sqlCall:
+		if val.aggregate() {
+			return true
+		}
+		for _, arg := range val.args {
+			if hasAggregate(arg) {
+				return true
+			}
+		}
+	case sqlBinary:
+		return hasAggregate(val.left) || hasAggregate(val.right)
+	case sqlLogic:
+		return hasAggregate(val.left) || hasAggregate(val.right)
+	case sqlNot:
+		return hasAggregate(val.inner)
+	case sqlIsNull:
+		return hasAggregate(val.inner)
+	case sqlLike:
+		return hasAggregate(val.inner) || hasAggregate(val.pattern)
+	case sqlIn:
+		if hasAggregate(val.inner) {
+			return true
+		}
+		for _, item := range val.list {
+			if hasAggregate(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sqlChildren 返回表达式的子表达式
+func sqlChildren(e sqlExpr) []sqlExpr {
+	switch val := e.(type) {
+	case sqlCall:
+		return val.args
+	case sqlBinary:
+		return []sqlExpr{val.left, val.right}
+	case sqlLogic:
+		return []sqlExpr{val.left, val.right}
+	case sqlNot:
+		return []sqlExpr{val.inner}
+	case sqlIsNull:
+		return []sqlExpr{val.inner}
+	case sqlLike:
+		return []sqlExpr{val.inner, val.pattern}
+	case sqlIn:
+		return append([]sqlExpr{val.inner}, val.list...)
+	default:
+		return nil
+	}
+}
+
+// bareColumn 返回聚合函数之外引用的第一个列名，与GROUP BY中的表达式相同的部分不算作列
+// aliases为SELECT中的列名，HAVING和ORDER BY可以直接使用
+func bareColumn(e sqlExpr, groupBy []sqlExpr, aliases map[string]bool) (string, bool) {
+	for _, g := range groupBy {
+		if reflect.DeepEqual(e, g) {
+			return "", false
+		}
+	}
+	switch val := e.(type) {
+	case sqlColumn:
+		return val.name, !aliases[val.name]
+	case sqlCall:
+		if val.aggregate() {
+			return "", false
+		}
+	}
+	for _, child := range sqlChildren(e) {
+		if name, ok := bareColumn(child, groupBy, aliases); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// checkAggregate 按组输出时列只能是GROUP BY中的表达式或在聚合函数中使用，没有GROUP BY时所有行为一组
+func (s *SQL) checkAggregate() error {
+	if !s.grouped() {
+		return nil
+	}
+	columnError := func(name string) error {
+		if len(s.groupBy) == 0 {
+			return fmt.Errorf("没有GROUP BY时列%s只能在聚合函数中使用", name)
+		}
+		return fmt.Errorf("列%s不在GROUP BY中，只能在聚合函数中使用", name)
+	}
+	aliases := map[string]bool{}
+	for _, item := range s.items {
+		if item.star {
+			if len(s.groupBy) == 0 {
+				return fmt.Errorf("没有GROUP BY时 * 不能与聚合函数同时使用")
+			}
+			return fmt.Errorf("有GROUP BY时不能使用 *")
+		}
+		if name, ok := bareColumn(item.expr, s.groupBy, nil); ok {
+			return columnError(name)
+		}
+		aliases[item.name] = true
+	}
+	exprs := []sqlExpr{s.having}
+	for _, item := range s.orderBy {
+		exprs = append(exprs, item.expr)
+	}
+	for _, e := range exprs {
+		if e == nil {
+			continue
+		}
+		if name, ok := bareColumn(e, s.groupBy, aliases); ok {
+			return columnError(name)
+		}
+	}
+	return nil
+}
+
+// grouped 是否按组输出，有GROUP BY或使用了聚合函数时所有行为一组
+func (s *SQL) grouped() bool {
+	if len(s.groupBy) > 0 || s.having != nil {
+		return true
+	}
+	for _, item := range s.items {
+		if !item.star && hasAggregate(item.expr) {
+			return true
+		}
+	}
+	for _, item := range s.orderBy {
+		if hasAggregate(item.expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run 对FROM路径下的值执行查询，rows应为object数组，返回输出行的数组
+func (s *SQL) Run(rows interface{}) ([]interface{}, types.ZfError) {
+	res, err := s.run(normalizeNumbers(rows))
+	if err != nil {
+		return nil, types.NewSQLError(s.text, err.Error())
+	}
+	return res, nil
+}
+
+// sqlResult 输出行和计算它的上下文，用于排序
+type sqlResult struct {
+	ctx *sqlContext
+	out map[string]interface{}
+}
+
+func (s *SQL) run(value interface{}) ([]interface{}, error) {
+	var rows []interface{}
+	switch val := value.(type) {
+	case nil:
+	case []interface{}:
+		rows = val
+	case map[string]interface{}:
+		rows = []interface{}{val}
+	default:
+		return nil, fmt.Errorf("%s应为object数组，实际为%s", s.From, describe(value))
+	}
+
+	if s.where != nil {
+		filtered := make([]interface{}, 0, len(rows))
+		for _, row := range rows {
+			v, err := s.where.eval(&sqlContext{row: row})
+			if err != nil {
+				return nil, err
+			}
+			if truthy(v) {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	var contexts []*sqlContext
+	if s.grouped() {
+		groups, err := s.group(rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			ctx := &sqlContext{group: group}
+			if len(group) > 0 {
+				ctx.row = group[0]
+			}
+			contexts = append(contexts, ctx)
+		}
+	} else {
+		for _, row := range rows {
+			contexts = append(contexts, &sqlContext{row: row})
+		}
+	}
+
+	results := make([]sqlResult, 0, len(contexts))
+	for _, ctx := range contexts {
+		out, err := s.project(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if s.having != nil {
+			// HAVING可以使用SELECT中的列别名
+			having := *ctx
+			having.out = out
+			v, err := s.having.eval(&having)
+			if err != nil {
+				return nil, err
+			}
+			if !truthy(v) {
+				continue
+			}
+		}
+		results = append(results, sqlResult{ctx: ctx, out: out})
+	}
+	if err := s.sort(results); err != nil {
+		return nil, err
+	}
+
+	if s.offset > len(results) {
+		results = nil
+	} else {
+		results = results[s.offset:]
+	}
+	if s.limit >= 0 && s.limit < len(results) {
+		results = results[:s.limit]
+	}
+	res := make([]interface{}, len(results))
+	for i, r := range results {
+		res[i] = r.out
+	}
+	return res, nil
+}
+
+// group 按GROUP BY的值分组，组按第一次出现的顺序排列，没有GROUP BY时所有行为一组
+func (s *SQL) group(rows []interface{}) ([][]interface{}, error) {
+	if len(s.groupBy) == 0 {
+		if rows == nil {
+			rows = []interface{}{}
+		}
+		return [][]interface{}{rows}, nil
+	}
+	var groups [][]interface{}
+	indexes := map[string]int{}
+	for _, row := range rows {
+		key := make([]interface{}, len(s.groupBy))
+		for i, e := range s.groupBy {
+			v, err := e.eval(&sqlContext{row: row})
+			if err != nil {
+				return nil, err
+			}
+			key[i] = v
+		}
+		text, err := toJSON(key)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := indexes[text]
+		if !ok {
+			i = len(groups)
+			indexes[text] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	return groups, nil
+}
+
+// project 计算SELECT的各列，* 展开为行的所有键
+func (s *SQL) project(ctx *sqlContext) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	for _, item := range s.items {
+		if item.star {
+			if m, ok := ctx.row.(map[string]interface{}); ok {
+				for k, v := range m {
+					out[k] = v
+				}
+			}
+			continue
+		}
+		v, err := item.expr.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out[item.name] = v
+	}
+	return out, nil
+}
+
+// sort 按ORDER BY稳定排序，null排在最前，表达式可以使用SELECT中的列别名
+func (s *SQL) sort(results []sqlResult) error {
+	if len(s.orderBy) == 0 {
+		return nil
+	}
+	keys := make([][]interface{}, len(results))
+	for i, r := range results {
+		ctx := *r.ctx
+		ctx.out = r.out
+		keys[i] = make([]interface{}, len(s.orderBy))
+		for j, item := range s.orderBy {
+			v, err := item.expr.eval(&ctx)
+			if err != nil {
+				return err
+			}
+			keys[i][j] = v
+		}
+	}
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		for j, item := range s.orderBy {
+			c := compare(keys[order[a]][j], keys[order[b]][j])
+			if item.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	sorted := make([]sqlResult, len(results))
+	for i, idx := range order {
+		sorted[i] = results[idx]
+	}
+	copy(results, sorted)
+	return nil
+}
@@ -0,0 +1,101 @@
+package query
+
+import (
+	"github.com/izern/zf/codec/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func init() {
+
+}
+
+const sqlDoc = `[
+  {"name": "hk-1", "type": "ss", "port": 443, "udp": true, "meta": {"region": "hk"}},
+  {"name": "us-1", "type": "vmess", "port": 8443, "meta": {"region": "us"}},
+  {"name": "hk-2", "type": "ss", "port": 80, "udp": false, "meta": {"region": "hk"}},
+  {"name": "jp-1", "type": "trojan", "port": 443}
+]`
+
+// runSQL 执行sql，每行输出转换为紧凑的json
+func runSQL(t *testing.T, text string) (string, []string, error) {
+	rows, err := (&json.JSONCodec{}).Unmarshal([]byte(sqlDoc))
+	assert.Nil(t, err)
+	s, zfErr := CompileSQL(text)
+	if zfErr != nil {
+		return "", nil, zfErr.Error()
+	}
+	assert.Equal(t, ".proxies", s.From)
+	res, zfErr := s.Run(rows)
+	if zfErr != nil {
+		return "", nil, zfErr.Error()
+	}
+	lines := make([]string, len(res))
+	for i, v := range res {
+		text, err := toJSON(v)
+		assert.Nil(t, err)
+		lines[i] = text
+	}
+	return strings.Join(lines, "\n"), s.Columns(), nil
+}
+
+func Test_SQL(t *testing.T) {
+	cases := map[string]string{
+		`SELECT name, port FROM .proxies WHERE type = 'ss' ORDER BY port`:      "{\"name\":\"hk-2\",\"port\":80}\n{\"name\":\"hk-1\",\"port\":443}",
+		`select name from .proxies where port >= 443 and type <> 'ss' limit 1`: `{"name":"us-1"}`,
+		`SELECT name FROM .proxies ORDER BY port DESC, name LIMIT 2 OFFSET 1`:  "{\"name\":\"hk-1\"}\n{\"name\":\"jp-1\"}",
+		`SELECT type, COUNT(*) AS n, SUM(port) total, AVG(port) FROM .proxies GROUP BY type ORDER BY n DESC, type`: "{\"AVG(port)\":261.5,\"n\":2,\"total\":523,\"type\":\"ss\"}\n" +
+			"{\"AVG(port)\":443,\"n\":1,\"total\":443,\"type\":\"trojan\"}\n{\"AVG(port)\":8443,\"n\":1,\"total\":8443,\"type\":\"vmess\"}",
+		`SELECT COUNT(*), COUNT(udp), MIN(port), MAX(name) FROM .proxies`:                             `{"COUNT(*)":4,"COUNT(udp)":2,"MAX(name)":"us-1","MIN(port)":80}`,
+		`SELECT COUNT(*) FROM .proxies WHERE port > 10000`:                                            `{"COUNT(*)":0}`,
+		`SELECT COUNT(*) c, MAX(port) + 1 m FROM .proxies HAVING c > 1 ORDER BY m`:                    `{"c":4,"m":8444}`,
+		`SELECT meta.region, COUNT(*) c FROM .proxies GROUP BY meta.region HAVING c > 1`:              `{"c":2,"meta.region":"hk"}`,
+		`SELECT LOWER(type) t, MAX(port) + 1 m FROM .proxies GROUP BY LOWER(type) ORDER BY t LIMIT 1`: `{"m":444,"t":"ss"}`,
+		`SELECT name FROM .proxies WHERE meta.region IS NULL`:                                         `{"name":"jp-1"}`,
+		`SELECT name FROM .proxies WHERE udp IS NOT NULL AND NOT udp`:                                 `{"name":"hk-2"}`,
+		`SELECT name FROM .proxies WHERE type IN ('vmess', 'trojan') ORDER BY name`:                   "{\"name\":\"jp-1\"}\n{\"name\":\"us-1\"}",
+		`SELECT name FROM .proxies WHERE name NOT LIKE 'HK%' AND name LIKE '__-1'`:                    "{\"name\":\"us-1\"}\n{\"name\":\"jp-1\"}",
+		`SELECT UPPER(name) AS n, port * 2 + 1 AS p FROM .proxies WHERE port < 100`:                   `{"n":"HK-2","p":161}`,
+		`SELECT COALESCE(udp, 'unknown') AS udp, LENGTH(name) FROM .proxies LIMIT 2`:                  "{\"LENGTH(name)\":4,\"udp\":true}\n{\"LENGTH(name)\":4,\"udp\":\"unknown\"}",
+		`SELECT * FROM .proxies WHERE port = 80`:                                                      `{"meta":{"region":"hk"},"name":"hk-2","port":80,"type":"ss","udp":false}`,
+		`SELECT "name" FROM .proxies WHERE udp = TRUE OR port = 8443`:                                 "{\"name\":\"hk-1\"}\n{\"name\":\"us-1\"}",
+		`SELECT name FROM .proxies WHERE udp = NULL`:                                                  ``,
+	}
+	for text, expected := range cases {
+		actual, _, err := runSQL(t, text)
+		assert.Nil(t, err, text)
+		assert.Equal(t, expected, actual, text)
+	}
+
+	_, columns, err := runSQL(t, `SELECT name, COUNT(*) AS c FROM .proxies GROUP BY name`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "c"}, columns)
+	_, columns, err = runSQL(t, `SELECT * FROM .proxies`)
+	assert.Nil(t, err)
+	assert.Nil(t, columns)
+}
+
+func Test_SQLError(t *testing.T) {
+	cases := map[string]string{
+		`SELECT name FROM .proxies WHERE COUNT(*) > 1`:          "sql SELECT name FROM .proxies WHERE COUNT(*) > 1 执行失败: 聚合函数COUNT不能用于WHERE",
+		`SELECT SUM(name) FROM .proxies`:                        `sql SELECT SUM(name) FROM .proxies 执行失败: SUM的参数应为数字，实际为string ("hk-1")`,
+		`SELECT name FROM .proxies WHERE`:                       "无法将解析化为sql语句格式, SELECT name FROM .proxies WHERE: 语句不完整",
+		`SELECT name .proxies`:                                  "无法将解析化为sql语句格式, SELECT name .proxies: 位置12应为FROM，实际为.proxies",
+		`SELECT FOO(name) FROM .proxies`:                        "无法将解析化为sql语句格式, SELECT FOO(name) FROM .proxies: 位置7的函数FOO不存在",
+		`SELECT name FROM .proxies LIMIT -1`:                    "无法将解析化为sql语句格式, SELECT name FROM .proxies LIMIT -1: 位置32的LIMIT后应为非负整数",
+		`SELECT name FROM .proxies WHERE name = 'a`:             "无法将解析化为sql语句格式, SELECT name FROM .proxies WHERE name = 'a: 位置39的'没有结束",
+		`SELECT name, COUNT(*) FROM .proxies`:                   "无法将解析化为sql语句格式, SELECT name, COUNT(*) FROM .proxies: 没有GROUP BY时列name只能在聚合函数中使用",
+		`SELECT *, COUNT(*) FROM .proxies`:                      "无法将解析化为sql语句格式, SELECT *, COUNT(*) FROM .proxies: 没有GROUP BY时 * 不能与聚合函数同时使用",
+		`SELECT COUNT(*) c FROM .proxies ORDER BY name`:         "无法将解析化为sql语句格式, SELECT COUNT(*) c FROM .proxies ORDER BY name: 没有GROUP BY时列name只能在聚合函数中使用",
+		`SELECT port FROM .proxies GROUP BY type`:               "无法将解析化为sql语句格式, SELECT port FROM .proxies GROUP BY type: 列port不在GROUP BY中，只能在聚合函数中使用",
+		`SELECT type FROM .proxies GROUP BY type ORDER BY port`: "无法将解析化为sql语句格式, SELECT type FROM .proxies GROUP BY type ORDER BY port: 列port不在GROUP BY中，只能在聚合函数中使用",
+		`SELECT * FROM .proxies GROUP BY type`:                  "无法将解析化为sql语句格式, SELECT * FROM .proxies GROUP BY type: 有GROUP BY时不能使用 *",
+	}
+	for text, expected := range cases {
+		_, _, err := runSQL(t, text)
+		if assert.NotNil(t, err, text) {
+			assert.Equal(t, expected, err.Error(), text)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package query
+
+import (
+	encjson "encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/izern/zf/codec"
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// typeName 返回jq中的类型名称
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		if _, _, _, ok := numeric(v); ok {
+			return "number"
+		}
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// truthy false和null为假，其他值为真
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	default:
+		return true
+	}
+}
+
+// numeric 将数字转换为int64或float64参与计算，isInt表示结果为i
+// 无法精确表示的json.Number按float64计算
+func numeric(v interface{}) (i int64, f float64, isInt bool, ok bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), 0, true, true
+	case int8:
+		return int64(n), 0, true, true
+	case int16:
+		return int64(n), 0, true, true
+	case int32:
+		return int64(n), 0, true, true
+	case int64:
+		return n, 0, true, true
+	case uint:
+		return numeric(uint64(n))
+	case uint8:
+		return int64(n), 0, true, true
+	case uint16:
+		return int64(n), 0, true, true
+	case uint32:
+		return int64(n), 0, true, true
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), 0, true, true
+		}
+		return 0, float64(n), false, true
+	case float32:
+		return 0, float64(n), false, true
+	case float64:
+		return 0, n, false, true
+	case encjson.Number:
+		if exact, ok := util.ExactNumber(n); ok {
+			return numeric(exact)
+		}
+		f, _ := n.Float64()
+		return 0, f, false, true
+	default:
+		return 0, 0, false, false
+	}
+}
+
+// toFloat 将数字转换为float64
+func toFloat(v interface{}) (float64, bool) {
+	i, f, isInt, ok := numeric(v)
+	if isInt {
+		return float64(i), ok
+	}
+	return f, ok
+}
+
+// toRat 将整数和json.Number转换为精确的有理数
+func toRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return new(big.Rat).SetUint64(n), true
+	case encjson.Number:
+		return new(big.Rat).SetString(string(n))
+	}
+	i, f, isInt, ok := numeric(v)
+	if !ok {
+		return nil, false
+	}
+	if isInt {
+		return new(big.Rat).SetInt64(i), true
+	}
+	r := new(big.Rat)
+	if r.SetFloat64(f) == nil {
+		return nil, false
+	}
+	return r, true
+}
+
+func isFloat(v interface{}) bool {
+	switch v.(type) {
+	case float32, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareNumbers 比较两个数字，包含小数时按float64比较
+func compareNumbers(a interface{}, b interface{}) int {
+	if !isFloat(a) && !isFloat(b) {
+		x, okX := toRat(a)
+		y, okY := toRat(b)
+		if okX && okY {
+			return x.Cmp(y)
+		}
+	}
+	x, _ := toFloat(a)
+	y, _ := toFloat(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// typeOrder jq的排序规则 null < false < true < 数字 < 字符串 < 数组 < object
+func typeOrder(v interface{}) int {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if val {
+			return 2
+		}
+		return 1
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	case map[string]interface{}:
+		return 6
+	default:
+		return 3
+	}
+}
+
+// compare 按jq的规则比较两个值
+func compare(a interface{}, b interface{}) int {
+	oa, ob := typeOrder(a), typeOrder(b)
+	if oa != ob {
+		if oa < ob {
+			return -1
+		}
+		return 1
+	}
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case []interface{}:
+		bv := b.([]interface{})
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compare(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInt(len(av), len(bv))
+	case map[string]interface{}:
+		bv := b.(map[string]interface{})
+		ka, kb := sortedKeys(av), sortedKeys(bv)
+		for i := 0; i < len(ka) && i < len(kb); i++ {
+			if c := strings.Compare(ka[i], kb[i]); c != 0 {
+				return c
+			}
+		}
+		if c := compareInt(len(ka), len(kb)); c != 0 {
+			return c
+		}
+		for _, k := range ka {
+			if c := compare(av[k], bv[k]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case nil, bool:
+		return 0
+	default:
+		return compareNumbers(a, b)
+	}
+}
+
+func compareInt(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortValues 按jq的规则稳定排序
+func sortValues(values []interface{}) {
+	sort.SliceStable(values, func(i, j int) bool {
+		return compare(values[i], values[j]) < 0
+	})
+}
+
+// toJSON 将值转换为紧凑的json
+func toJSON(v interface{}) (string, error) {
+	opts := codec.DefaultMarshalOptions()
+	opts.Compact = true
+	opts.EscapeHTML = false
+	text, err := (&json.JSONCodec{}).MarshalWithOptions(v, opts)
+	if err != nil {
+		return "", err.Error()
+	}
+	return string(text), nil
+}
+
+// toString 字符串原样返回，其他值转换为json
+func toString(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return toJSON(v)
+}
+
+// describe 用于错误信息的值描述，过长时截断
+func describe(v interface{}) string {
+	text, err := toJSON(v)
+	if err != nil {
+		text = fmt.Sprint(v)
+	}
+	if len(text) > 30 {
+		text = text[:27] + "..."
+	}
+	return fmt.Sprintf("%s (%s)", typeName(v), text)
+}
+
+// arithmetic 计算两个值的 + - * / %
+func arithmetic(op string, a interface{}, b interface{}) (interface{}, error) {
+	ai, af, aInt, aNum := numeric(a)
+	bi, bf, bInt, bNum := numeric(b)
+	if aNum && bNum {
+		if aInt && bInt {
+			if res, ok := intArithmetic(op, ai, bi); ok {
+				return res, nil
+			}
+		}
+		if aInt {
+			af = float64(ai)
+		}
+		if bInt {
+			bf = float64(bi)
+		}
+		switch op {
+		case "+":
+			return af + bf, nil
+		case "-":
+			return af - bf, nil
+		case "*":
+			return af * bf, nil
+		case "/":
+			if bf == 0 {
+				return nil, fmt.Errorf("%s和%s不能相除，除数为0", describe(a), describe(b))
+			}
+			return af / bf, nil
+		default:
+			x, y := int64(af), int64(bf)
+			if y == 0 {
+				return nil, fmt.Errorf("%s和%s不能取余，除数为0", describe(a), describe(b))
+			}
+			return x % y, nil
+		}
+	}
+
+	switch op {
+	case "+":
+		if a == nil {
+			return b, nil
+		}
+		if b == nil {
+			return a, nil
+		}
+		switch av := a.(type) {
+		case string:
+			if bv, ok := b.(string); ok {
+				return av + bv, nil
+			}
+		case []interface{}:
+			if bv, ok := b.([]interface{}); ok {
+				res := make([]interface{}, 0, len(av)+len(bv))
+				return append(append(res, av...), bv...), nil
+			}
+		case map[string]interface{}:
+			if bv, ok := b.(map[string]interface{}); ok {
+				res := make(map[string]interface{}, len(av)+len(bv))
+				for k, v := range av {
+					res[k] = v
+				}
+				for k, v := range bv {
+					res[k] = v
+				}
+				return res, nil
+			}
+		}
+	case "-":
+		av, okA := a.([]interface{})
+		bv, okB := b.([]interface{})
+		if okA && okB {
+			res := make([]interface{}, 0, len(av))
+			for _, item := range av {
+				found := false
+				for _, other := range bv {
+					if compare(item, other) == 0 {
+						found = true
+						break
+					}
+				}
+				if !found {
+					res = append(res, item)
+				}
+			}
+			return res, nil
+		}
+	case "*":
+		av, okA := a.(map[string]interface{})
+		bv, okB := b.(map[string]interface{})
+		if okA && okB {
+			return deepMerge(av, bv), nil
+		}
+	case "/":
+		av, okA := a.(string)
+		bv, okB := b.(string)
+		if okA && okB {
+			return splitString(av, bv), nil
+		}
+	}
+	return nil, fmt.Errorf("%s和%s不能进行%s运算", describe(a), describe(b), op)
+}
+
+// intArithmetic 整数运算，溢出或除不尽时返回false改用float64
+func intArithmetic(op string, a int64, b int64) (interface{}, bool) {
+	switch op {
+	case "+":
+		res := a + b
+		if (res > a) == (b > 0) {
+			return res, true
+		}
+	case "-":
+		res := a - b
+		if (res < a) == (b > 0) {
+			return res, true
+		}
+	case "*":
+		if a == 0 || b == 0 {
+			return 0, true
+		}
+		res := a * b
+		if res/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64) {
+			return res, true
+		}
+	case "/":
+		if b != 0 && a%b == 0 && !(a == math.MinInt64 && b == -1) {
+			return a / b, true
+		}
+	case "%":
+		if b != 0 {
+			if b == -1 {
+				return 0, true
+			}
+			return a % b, true
+		}
+	}
+	return nil, false
+}
+
+// deepMerge 递归合并object，b中的值覆盖a中的值
+func deepMerge(a map[string]interface{}, b map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(a)+len(b))
+	for k, v := range a {
+		res[k] = v
+	}
+	for k, v := range b {
+		av, okA := res[k].(map[string]interface{})
+		bv, okB := v.(map[string]interface{})
+		if okA && okB {
+			res[k] = deepMerge(av, bv)
+			continue
+		}
+		res[k] = v
+	}
+	return res
+}
+
+func splitString(s string, sep string) []interface{} {
+	if s == "" {
+		return []interface{}{}
+	}
+	parts := strings.Split(s, sep)
+	res := make([]interface{}, len(parts))
+	for i, part := range parts {
+		res[i] = part
+	}
+	return res
+}
@@ -0,0 +1,170 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+func init() {
+
+}
+
+// Draft 推断的schema使用的版本
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// inferFormats 推断字符串format时依次尝试的格式，所有字符串都满足时生成format
+var inferFormats = []string{"date-time", "date", "time", "ipv4", "ipv6", "email", "uuid", "uri"}
+
+// 生成type时的顺序
+var typeOrder = []string{"null", "boolean", "integer", "number", "string", "array", "object"}
+
+// InferOptions 推断schema的选项
+type InferOptions struct {
+	// MaxEnum 字符串的不同值不超过该数量且每个值平均至少出现两次时生成enum，为0时不生成enum
+	MaxEnum int
+}
+
+// sample 同一位置所有样本值的汇总
+type sample struct {
+	types map[string]int
+	// strings 不同的字符串，按第一次出现的顺序
+	strings  []string
+	counts   map[string]int
+	formats  map[string]int
+	objects  int
+	keys     []string
+	props    map[string]*sample
+	propSeen map[string]int
+	items    *sample
+}
+
+func newSample() *sample {
+	return &sample{
+		types:    map[string]int{},
+		counts:   map[string]int{},
+		formats:  map[string]int{},
+		props:    map[string]*sample{},
+		propSeen: map[string]int{},
+	}
+}
+
+// Infer 由一个或多个样本文档推断JSON Schema，包括类型、必需的键、低基数字符串的enum、数组元素的schema和常见的format
+// 所有样本都包含的键为required，数组中所有元素合并推断为items
+func Infer(docs []interface{}, opts InferOptions) map[string]interface{} {
+	root := newSample()
+	for _, doc := range docs {
+		root.add(doc)
+	}
+	res := root.schema(opts)
+	res["$schema"] = Draft
+	return res
+}
+
+func (s *sample) add(v interface{}) {
+	t := typeOf(v)
+	s.types[t]++
+	switch val := v.(type) {
+	case string:
+		if s.counts[val] == 0 {
+			s.strings = append(s.strings, val)
+		}
+		s.counts[val]++
+		for _, format := range inferFormats {
+			if format == "uri" && !strings.Contains(val, "://") {
+				// 只把带 :// 的字符串推断为uri，避免 a:b 这样的值
+				continue
+			}
+			if valid, _ := CheckFormat(format, val); valid {
+				s.formats[format]++
+			}
+		}
+	case map[string]interface{}:
+		s.objects++
+		for _, key := range sortedKeys(val) {
+			prop, ok := s.props[key]
+			if !ok {
+				prop = newSample()
+				s.props[key] = prop
+				s.keys = append(s.keys, key)
+			}
+			s.propSeen[key]++
+			prop.add(val[key])
+		}
+	case []interface{}:
+		if s.items == nil {
+			s.items = newSample()
+		}
+		for _, item := range val {
+			s.items.add(item)
+		}
+	}
+}
+
+func (s *sample) schema(opts InferOptions) map[string]interface{} {
+	res := map[string]interface{}{}
+	var names []string
+	for _, t := range typeOrder {
+		if s.types[t] == 0 || t == "integer" && s.types["number"] > 0 {
+			continue
+		}
+		names = append(names, t)
+	}
+	switch len(names) {
+	case 0:
+		// 空数组的元素，不做限制
+		return res
+	case 1:
+		res["type"] = names[0]
+	default:
+		list := make([]interface{}, len(names))
+		for i, name := range names {
+			list[i] = name
+		}
+		res["type"] = list
+	}
+
+	if n := s.types["string"]; n > 0 {
+		if len(names) == 1 && opts.MaxEnum > 0 && len(s.strings) <= opts.MaxEnum && n >= 2*len(s.strings) {
+			enum := make([]interface{}, len(s.strings))
+			for i, str := range s.strings {
+				enum[i] = str
+			}
+			res["enum"] = enum
+		} else {
+			for _, format := range inferFormats {
+				if s.formats[format] == n {
+					res["format"] = format
+					break
+				}
+			}
+			if _, ok := res["format"]; !ok && s.formats["ipv4"] > 0 && s.formats["ipv4"]+s.formats["ipv6"] == n {
+				// 同时包含ipv4和ipv6地址
+				res["anyOf"] = []interface{}{
+					map[string]interface{}{"format": "ipv4"},
+					map[string]interface{}{"format": "ipv6"},
+				}
+			}
+		}
+	}
+	if s.objects > 0 {
+		properties := map[string]interface{}{}
+		var required []interface{}
+		for _, key := range s.keys {
+			properties[key] = s.props[key].schema(opts)
+			if s.propSeen[key] == s.objects {
+				required = append(required, key)
+			}
+		}
+		res["properties"] = properties
+		if len(required) > 0 {
+			sort.Slice(required, func(i, j int) bool {
+				return required[i].(string) < required[j].(string)
+			})
+			res["required"] = required
+		}
+	}
+	if s.items != nil {
+		res["items"] = s.items.schema(opts)
+	}
+	return res
+}
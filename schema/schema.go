@@ -0,0 +1,283 @@
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/izern/zf/types"
+)
+
+func init() {
+
+}
+
+// defaultBase 没有$id的schema使用的基础URI，用于解析相对的$ref
+const defaultBase = "zf:///schema.json"
+
+// located schema和解析它内部$ref使用的基础URI
+type located struct {
+	schema interface{}
+	base   string
+}
+
+// Schema 编译后的JSON Schema，支持draft 2020-12的关键字
+// $ref、$dynamicRef只支持文档内部的引用，包括 #/$defs/a 这样的JSON Pointer、$anchor 和 $id
+type Schema struct {
+	root located
+	// resources $id对应的schema，键为不含fragment的URI
+	resources map[string]located
+	// anchors $anchor和$dynamicAnchor对应的schema，键为 URI#anchor
+	anchors map[string]located
+	// dynamicAnchors $dynamicAnchor对应的schema，键为 URI#anchor
+	dynamicAnchors map[string]located
+	patterns       map[string]*regexp.Regexp
+	refs           []located
+	// AssertFormat 为true时format作为断言校验，默认与规范一致只作为注解
+	AssertFormat bool
+}
+
+// 值为单个schema的关键字
+var schemaKeywords = []string{"additionalProperties", "propertyNames", "items", "contains", "not", "if", "then", "else", "unevaluatedItems", "unevaluatedProperties"}
+
+// 值为schema数组的关键字
+var schemaArrayKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+
+// 值为object，object的每个值为schema的关键字
+var schemaMapKeywords = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas"}
+
+// Compile 编译schema，schema可以由任意已注册的格式解析得到
+func Compile(schema interface{}) (*Schema, types.ZfError) {
+	s := &Schema{
+		resources:      map[string]located{},
+		anchors:        map[string]located{},
+		dynamicAnchors: map[string]located{},
+		patterns:       map[string]*regexp.Regexp{},
+	}
+	base, err := s.baseOf(schema, defaultBase)
+	if err != nil {
+		return nil, err
+	}
+	s.root = located{schema: schema, base: base}
+	s.resources[base] = s.root
+	if err = s.walk(schema, defaultBase, "#"); err != nil {
+		return nil, err
+	}
+	for _, ref := range s.refs {
+		if _, err = s.resolveRef(ref.schema.(string), ref.base); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// walk 记录所有的$id、$anchor、$dynamicAnchor，编译正则并检查schema的格式
+func (s *Schema) walk(schema interface{}, base string, pointer string) types.ZfError {
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		if _, ok = schema.(bool); ok {
+			return nil
+		}
+		return schemaError(pointer, "schema应为object或bool")
+	}
+	base, err := s.baseOf(m, base)
+	if err != nil {
+		return err
+	}
+	if _, ok := m["$id"]; ok {
+		s.resources[base] = located{schema: m, base: base}
+	}
+	for _, keyword := range []string{"$anchor", "$dynamicAnchor"} {
+		if v, ok := m[keyword]; ok {
+			anchor, ok := v.(string)
+			if !ok {
+				return schemaError(pointer+"/"+keyword, "应为字符串")
+			}
+			s.anchors[base+"#"+anchor] = located{schema: m, base: base}
+			if keyword == "$dynamicAnchor" {
+				s.dynamicAnchors[base+"#"+anchor] = located{schema: m, base: base}
+			}
+		}
+	}
+	for _, keyword := range []string{"$ref", "$dynamicRef"} {
+		if v, ok := m[keyword]; ok {
+			ref, ok := v.(string)
+			if !ok {
+				return schemaError(pointer+"/"+keyword, "应为字符串")
+			}
+			s.refs = append(s.refs, located{schema: ref, base: base})
+		}
+	}
+	if v, ok := m["pattern"]; ok {
+		pattern, ok := v.(string)
+		if !ok {
+			return schemaError(pointer+"/pattern", "应为字符串")
+		}
+		if err := s.compilePattern(pattern, pointer+"/pattern"); err != nil {
+			return err
+		}
+	}
+	if v, ok := m["patternProperties"].(map[string]interface{}); ok {
+		for pattern := range v {
+			if err := s.compilePattern(pattern, pointer+"/patternProperties"); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, keyword := range schemaKeywords {
+		if v, ok := m[keyword]; ok {
+			if err := s.walk(v, base, pointer+"/"+keyword); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range schemaArrayKeywords {
+		v, ok := m[keyword]
+		if !ok {
+			continue
+		}
+		items, ok := v.([]interface{})
+		if !ok || len(items) == 0 {
+			return schemaError(pointer+"/"+keyword, "应为非空的schema数组")
+		}
+		for i, item := range items {
+			if err := s.walk(item, base, fmt.Sprintf("%s/%s/%d", pointer, keyword, i)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range schemaMapKeywords {
+		v, ok := m[keyword]
+		if !ok {
+			continue
+		}
+		items, ok := v.(map[string]interface{})
+		if !ok {
+			return schemaError(pointer+"/"+keyword, "应为object")
+		}
+		for _, key := range sortedKeys(items) {
+			if err := s.walk(items[key], base, pointer+"/"+keyword+"/"+escapePointer(key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) compilePattern(pattern string, pointer string) types.ZfError {
+	if _, ok := s.patterns[pattern]; ok {
+		return nil
+	}
+	re, e := regexp.Compile(pattern)
+	if e != nil {
+		return schemaError(pointer, fmt.Sprintf("正则%s格式错误: %v", pattern, e))
+	}
+	s.patterns[pattern] = re
+	return nil
+}
+
+// baseOf 返回schema的基础URI，包含$id时相对于上层的基础URI解析
+func (s *Schema) baseOf(schema interface{}, base string) (string, types.ZfError) {
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		return base, nil
+	}
+	v, ok := m["$id"]
+	if !ok {
+		return base, nil
+	}
+	id, ok := v.(string)
+	if !ok {
+		return "", schemaError("$id", "应为字符串")
+	}
+	u, err := resolveURI(base, id)
+	if err != nil {
+		return "", err
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// resolveRef 解析$ref，返回引用的schema和它的基础URI
+func (s *Schema) resolveRef(ref string, base string) (located, types.ZfError) {
+	u, err := resolveURI(base, ref)
+	if err != nil {
+		return located{}, err
+	}
+	fragment := u.Fragment
+	u.Fragment = ""
+	res, ok := s.resources[u.String()]
+	if !ok {
+		return located{}, schemaError("$ref", fmt.Sprintf("无法解析%s，只支持文档内部的引用", ref))
+	}
+	if fragment == "" {
+		return res, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		target, ok := s.anchors[u.String()+"#"+fragment]
+		if !ok {
+			return located{}, schemaError("$ref", fmt.Sprintf("无法解析%s，anchor %s不存在", ref, fragment))
+		}
+		return target, nil
+	}
+	cur := res
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		var next interface{}
+		switch v := cur.schema.(type) {
+		case map[string]interface{}:
+			next, ok = v[token]
+		case []interface{}:
+			var i int
+			if _, e := fmt.Sscanf(token, "%d", &i); e == nil && i >= 0 && i < len(v) {
+				next, ok = v[i], true
+			} else {
+				ok = false
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return located{}, schemaError("$ref", fmt.Sprintf("无法解析%s，路径不存在", ref))
+		}
+		nextBase, err := s.baseOf(next, cur.base)
+		if err != nil {
+			return located{}, err
+		}
+		cur = located{schema: next, base: nextBase}
+	}
+	return cur, nil
+}
+
+func resolveURI(base string, ref string) (*url.URL, types.ZfError) {
+	b, e := url.Parse(base)
+	if e != nil {
+		return nil, schemaError("$id", fmt.Sprintf("%s不是合法的URI", base))
+	}
+	r, e := url.Parse(ref)
+	if e != nil {
+		return nil, schemaError("$ref", fmt.Sprintf("%s不是合法的URI", ref))
+	}
+	return b.ResolveReference(r), nil
+}
+
+func schemaError(pointer string, detail string) types.ZfError {
+	return types.NewFormatError(fmt.Sprintf("%s: %s", pointer, detail), "json schema")
+}
+
+// escapePointer 按JSON Pointer转义 ~ 和 /
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,207 @@
+package schema
+
+import (
+	"fmt"
+	"github.com/izern/zf/codec/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func init() {
+
+}
+
+func parseJSON(t *testing.T, text string) interface{} {
+	v, err := (&json.JSONCodec{}).Unmarshal([]byte(text))
+	assert.Nil(t, err)
+	return v
+}
+
+// runValidate 校验文档，每处违规输出为 路径 关键字，用换行连接
+func runValidate(t *testing.T, schemaText string, doc string) string {
+	s, err := Compile(parseJSON(t, schemaText))
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	violations, err := s.Validate(parseJSON(t, doc))
+	assert.Nil(t, err)
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = v.Path + " " + v.Keyword
+	}
+	return strings.Join(lines, "\n")
+}
+
+func Test_Validate(t *testing.T) {
+	cases := []struct {
+		schema, doc, expected string
+	}{
+		{`{"type": "integer"}`, `1.0`, ""},
+		{`{"type": "integer"}`, `1.5`, ". type"},
+		{`{"type": ["string", "null"]}`, `null`, ""},
+		{`{"type": "number"}`, `12345678901234567890`, ""},
+		{`true`, `{"a": 1}`, ""},
+		{`false`, `1`, ". false"},
+		{`{"enum": [1, "a", {"b": [1]}]}`, `{"b": [1.0]}`, ""},
+		{`{"enum": [1, "a"]}`, `2`, ". enum"},
+		{`{"const": {"a": 1}}`, `{"a": 2}`, ". const"},
+		{`{"multipleOf": 0.1}`, `0.3`, ""},
+		{`{"multipleOf": 2}`, `7`, ". multipleOf"},
+		{`{"minimum": 1, "exclusiveMaximum": 10}`, `10`, ". exclusiveMaximum"},
+		{`{"exclusiveMinimum": 1, "maximum": 10}`, `1`, ". exclusiveMinimum"},
+		{`{"minLength": 2, "maxLength": 3}`, `"中文"`, ""},
+		{`{"maxLength": 1, "pattern": "^[a-z]+$"}`, `"AB"`, ". maxLength\n. pattern"},
+		{`{"minItems": 3, "uniqueItems": true}`, `[1, 1.0]`, ". minItems\n.[1] uniqueItems"},
+		{`{"prefixItems": [{"type": "string"}], "items": false}`, `["a", 1, 2]`, ".[1] items\n.[2] items"},
+		{`{"items": {"type": "integer"}}`, `[1, "a"]`, ".[1] type"},
+		{`{"contains": {"type": "string"}}`, `[1, 2]`, ". contains"},
+		{`{"contains": {"type": "string"}, "minContains": 2, "maxContains": 2}`, `["a", 1, "b"]`, ""},
+		{`{"contains": {"type": "string"}, "maxContains": 1}`, `["a", "b"]`, ". maxContains"},
+		{`{"required": ["a", "b"], "properties": {"a": {"type": "string"}}}`, `{"a": 1}`, ".a type\n. required"},
+		{`{"properties": {"a": true}, "patternProperties": {"^x-": {"type": "integer"}}, "additionalProperties": false}`,
+			`{"a": 1, "x-b": "s", "c": 1}`, ".c additionalProperties\n.x-b type"},
+		{`{"propertyNames": {"maxLength": 2}, "minProperties": 3}`, `{"abc": 1}`, ".abc propertyNames\n. minProperties"},
+		{`{"dependentRequired": {"a": ["b"]}, "dependentSchemas": {"b": {"required": ["c"]}}}`, `{"a": 1}`, ". dependentRequired"},
+		{`{"dependentSchemas": {"b": {"required": ["c"]}}}`, `{"b": 1}`, ". required"},
+		{`{"allOf": [{"type": "integer"}, {"minimum": 5}]}`, `3`, ". minimum"},
+		{`{"anyOf": [{"type": "string"}, {"minimum": 5}]}`, `3`, ". anyOf"},
+		{`{"oneOf": [{"type": "integer"}, {"minimum": 5}]}`, `6`, ". oneOf"},
+		{`{"oneOf": [{"type": "integer"}, {"minimum": 5}]}`, `3`, ""},
+		{`{"not": {"type": "null"}}`, `null`, ". not"},
+		{`{"if": {"properties": {"type": {"const": "ss"}}}, "then": {"required": ["cipher"]}, "else": {"required": ["uuid"]}}`,
+			`{"type": "ss"}`, ". required"},
+		{`{"$defs": {"port": {"type": "integer", "maximum": 65535}}, "properties": {"port": {"$ref": "#/$defs/port"}}}`,
+			`{"port": 70000}`, ".port maximum"},
+		{`{"$defs": {"n": {"$anchor": "node", "properties": {"next": {"$ref": "#node"}, "v": {"type": "integer"}}}}, "$ref": "#node"}`,
+			`{"v": 1, "next": {"v": "a", "next": {"v": 3}}}`, ".next.v type"},
+		{`{"$id": "https://example.com/root.json", "$defs": {"s": {"$id": "str.json", "type": "string"}}, "items": {"$ref": "str.json"}}`,
+			`["a", 1]`, ".[1] type"},
+		{`{"properties": {"a": true}, "allOf": [{"properties": {"b": true}}], "unevaluatedProperties": false}`,
+			`{"a": 1, "b": 2, "c": 3}`, ".c unevaluatedProperties"},
+		{`{"prefixItems": [true], "contains": {"type": "string"}, "unevaluatedItems": false}`, `[1, "a", 2]`, ".[2] unevaluatedItems"},
+		{`{"properties": {"a.b": {"type": "string"}}, "items": true}`, `{"a.b": 1}`, `.a\.b type`},
+		{`{"$dynamicAnchor": "node", "properties": {"children": {"items": {"$dynamicRef": "#node"}}}}`,
+			`{"children": [{"children": []}]}`, ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, runValidate(t, c.schema, c.doc), c.schema+" "+c.doc)
+	}
+}
+
+func Test_ValidateFormat(t *testing.T) {
+	s, err := Compile(parseJSON(t, `{"properties": {"ip": {"format": "ipv4"}, "at": {"format": "date-time"}, "x": {"format": "unknown"}}}`))
+	assert.Nil(t, err)
+	doc := parseJSON(t, `{"ip": "1.2.3", "at": "2021-07-23T10:00:00Z", "x": "y"}`)
+	violations, err := s.Validate(doc)
+	assert.Nil(t, err)
+	assert.Empty(t, violations)
+
+	s.AssertFormat = true
+	violations, err = s.Validate(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(violations))
+	assert.Equal(t, ".ip: \"1.2.3\"不是合法的ipv4格式 (#/properties/ip/format)", violations[0].String())
+
+	for format, values := range map[string][2]string{
+		"date":     {"2021-07-23", "2021-7-23"},
+		"time":     {"10:00:00+08:00", "25:00:00Z"},
+		"email":    {"a@b.com", "a.b.com"},
+		"hostname": {"ssl.tcpbbr.net", "-a.net"},
+		"ipv6":     {"::1", "1.2.3.4"},
+		"uri":      {"https://a.com/b?c=d", "/b"},
+		"uuid":     {"7b4066ae-accc-11eb-a8bf-f23c91cfbbc9", "7b4066ae"},
+		"duration": {"P1DT2H", "PT"},
+	} {
+		valid, known := CheckFormat(format, values[0])
+		assert.True(t, valid && known, format)
+		valid, _ = CheckFormat(format, values[1])
+		assert.False(t, valid, format)
+	}
+}
+
+func Test_CompileError(t *testing.T) {
+	for schema, message := range map[string]string{
+		`{"$ref": "#/$defs/none"}`:                   "#/$defs/none",
+		`{"$ref": "https://example.com/other.json"}`: "只支持文档内部的引用",
+		`{"pattern": "("}`:                           "#/pattern",
+		`{"properties": {"a": 1}}`:                   "#/properties/a",
+		`{"allOf": []}`:                              "#/allOf",
+	} {
+		_, err := Compile(parseJSON(t, schema))
+		if assert.NotNil(t, err, schema) {
+			assert.Contains(t, err.Error().Error(), message)
+		}
+	}
+
+	// 循环引用只报告引用的值和发现循环的位置
+	for schema, message := range map[string]string{
+		`{"$ref": "#"}`: "无法将解析化为json schema格式, #/$ref: $ref # 存在循环引用，校验.时没有消耗输入",
+		`{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`:                                     "无法将解析化为json schema格式, #/$ref/$ref: $ref #/$defs/a 存在循环引用，校验.时没有消耗输入",
+		`{"$defs": {"a": {"allOf": [{"$ref": "#/$defs/a"}]}}, "properties": {"p": {"$ref": "#/$defs/a"}}}`: "无法将解析化为json schema格式, #/properties/p/$ref/allOf/0/$ref: $ref #/$defs/a 存在循环引用，校验.p时没有消耗输入",
+	} {
+		s, err := Compile(parseJSON(t, schema))
+		assert.Nil(t, err, schema)
+		_, err = s.Validate(parseJSON(t, `{"p": 1}`))
+		if assert.NotNil(t, err, schema) {
+			assert.Equal(t, message, err.Error().Error(), schema)
+		}
+	}
+
+	// 递归的schema按文档的层级展开，不是循环引用
+	s, err := Compile(parseJSON(t, `{"type": "object", "additionalProperties": {"$ref": "#"}}`))
+	assert.Nil(t, err)
+	violations, err := s.Validate(parseJSON(t, `{"a": {"b": {"c": 1}}}`))
+	assert.Nil(t, err)
+	assert.Len(t, violations, 1)
+}
+
+func Test_Infer(t *testing.T) {
+	docs := []interface{}{
+		parseJSON(t, `{"port": 7890, "mode": "rule", "created": "2021-07-23", "home": "https://example.com",
+			"proxies": [{"name": "a", "type": "ss", "server": "1.2.3.4", "port": 443}, {"name": "b", "type": "ss", "server": "::1", "port": 80, "udp": true}]}`),
+		parseJSON(t, `{"port": 1.5, "mode": "rule", "created": "2021-07-24", "home": "https://example.org", "tags": [], "extra": null}`),
+	}
+	s := Infer(docs, InferOptions{MaxEnum: 3})
+	text, err := toJSON(s)
+	assert.Nil(t, err)
+	fmt.Println(text)
+	assert.Equal(t, Draft, s["$schema"])
+	assert.Equal(t, []interface{}{"created", "home", "mode", "port"}, s["required"])
+
+	properties := s["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "number"}, properties["port"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "enum": []interface{}{"rule"}}, properties["mode"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date"}, properties["created"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "uri"}, properties["home"])
+	assert.Equal(t, map[string]interface{}{"type": "null"}, properties["extra"])
+	assert.Equal(t, map[string]interface{}{"type": "array", "items": map[string]interface{}{}}, properties["tags"])
+
+	proxy := properties["proxies"].(map[string]interface{})["items"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"name", "port", "server", "type"}, proxy["required"])
+	proxyProperties := proxy["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "integer"}, proxyProperties["port"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, proxyProperties["name"])
+	assert.NotNil(t, proxyProperties["server"].(map[string]interface{})["anyOf"])
+
+	// 推断的schema可以校验所有样本
+	compiled, zfErr := Compile(parseJSON(t, text))
+	assert.Nil(t, zfErr)
+	compiled.AssertFormat = true
+	for _, doc := range docs {
+		violations, zfErr := compiled.Validate(doc)
+		assert.Nil(t, zfErr)
+		assert.Empty(t, violations)
+	}
+
+	s = Infer([]interface{}{parseJSON(t, `[1, "a", null, "a"]`)}, InferOptions{MaxEnum: 0})
+	assert.Equal(t, map[string]interface{}{"type": []interface{}{"null", "integer", "string"}}, s["items"])
+}
+
+func toJSON(v interface{}) (string, error) {
+	text, err := (&json.JSONCodec{}).Marshal(v)
+	if err != nil {
+		return "", err.Error()
+	}
+	return string(text), nil
+}
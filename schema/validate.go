@@ -0,0 +1,704 @@
+package schema
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/izern/zf/cmd"
+	"github.com/izern/zf/codec"
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/types"
+	"github.com/izern/zf/util"
+)
+
+func init() {
+
+}
+
+// maxDepth $ref嵌套的最大深度，用于限制递归schema校验很深的文档
+const maxDepth = 512
+
+// Violation 一处不满足schema的值
+type Violation struct {
+	// Path 值在文档中的路径
+	Path string
+	// Keyword 校验失败的关键字
+	Keyword string
+	// SchemaPath 校验失败的关键字在schema中的位置，JSON Pointer格式
+	SchemaPath string
+	Message    string
+}
+
+// ToValue 转换为可以按任意格式输出的object
+func (v Violation) ToValue() map[string]interface{} {
+	return map[string]interface{}{
+		"path":       v.Path,
+		"keyword":    v.Keyword,
+		"schemaPath": v.SchemaPath,
+		"message":    v.Message,
+	}
+}
+
+// String 按 路径: 说明 (schema中的位置) 输出
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Path, v.Message, v.SchemaPath)
+}
+
+// result 一个schema的校验结果，以及校验通过时收集的已校验的键和元素，用于unevaluatedProperties和unevaluatedItems
+type result struct {
+	violations []Violation
+	props      map[string]bool
+	items      map[int]bool
+	allItems   bool
+}
+
+func (r *result) valid() bool {
+	return len(r.violations) == 0
+}
+
+func (r *result) fail(path string, keyword string, schemaPath string, format string, args ...interface{}) {
+	r.violations = append(r.violations, Violation{
+		Path:       path,
+		Keyword:    keyword,
+		SchemaPath: schemaPath,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+// add 合并子schema的结果，只有子schema校验通过时才合并已校验的键和元素
+func (r *result) add(sub *result) {
+	r.violations = append(r.violations, sub.violations...)
+	if sub.valid() {
+		r.annotate(sub)
+	}
+}
+
+func (r *result) annotate(sub *result) {
+	for k := range sub.props {
+		r.prop(k)
+	}
+	for i := range sub.items {
+		r.item(i)
+	}
+	r.allItems = r.allItems || sub.allItems
+}
+
+func (r *result) prop(key string) {
+	if r.props == nil {
+		r.props = map[string]bool{}
+	}
+	r.props[key] = true
+}
+
+func (r *result) item(index int) {
+	if r.items == nil {
+		r.items = map[int]bool{}
+	}
+	r.items[index] = true
+}
+
+type validator struct {
+	s *Schema
+	// scopes 动态作用域中经过的schema资源，用于解析$dynamicRef
+	scopes []string
+	// active 正在校验的引用目标和值的路径，再次进入时说明存在不消耗输入的循环引用
+	active map[string]bool
+	depth  int
+}
+
+// Validate 按schema校验文档，返回所有不满足schema的值，文档满足schema时返回空
+func (s *Schema) Validate(doc interface{}) ([]Violation, types.ZfError) {
+	v := &validator{s: s, active: map[string]bool{}}
+	if key, ok := activeKey(s.root.schema, "."); ok {
+		v.active[key] = true
+	}
+	res, err := v.validate(s.root.schema, s.root.base, doc, ".", "#")
+	if err != nil {
+		return nil, err
+	}
+	return res.violations, nil
+}
+
+func (v *validator) validate(schema interface{}, base string, inst interface{}, path string, schemaPath string) (*result, types.ZfError) {
+	res := &result{}
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		if b, _ := schema.(bool); !b {
+			res.fail(path, "false", schemaPath, "schema为false，不允许任何值")
+		}
+		return res, nil
+	}
+	v.depth++
+	defer func() { v.depth-- }()
+	if v.depth > maxDepth {
+		return nil, schemaError("$ref", fmt.Sprintf("校验%s时$ref嵌套超过%d层", path, maxDepth))
+	}
+	base, err := v.s.baseOf(m, base)
+	if err != nil {
+		return nil, err
+	}
+	v.scopes = append(v.scopes, base)
+	defer func() { v.scopes = v.scopes[:len(v.scopes)-1] }()
+
+	for _, step := range []func(map[string]interface{}, string, interface{}, string, string, *result) types.ZfError{
+		v.validateRef,
+		v.validateApplicators,
+		v.validateGeneric,
+		v.validateNumber,
+		v.validateString,
+		v.validateArray,
+		v.validateObject,
+		// unevaluated*需要在其他关键字之后校验
+		v.validateUnevaluated,
+	} {
+		if err := step(m, base, inst, path, schemaPath, res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// validateRef 校验$ref和$dynamicRef
+func (v *validator) validateRef(m map[string]interface{}, base string, inst interface{}, path string, schemaPath string, res *result) types.ZfError {
+	if ref, ok := m["$ref"].(string); ok {
+		target, err := v.s.resolveRef(ref, base)
+		if err != nil {
+			return err
+		}
+		sub, err := v.follow("$ref", ref, target, inst, path, schemaPath)
+		if err != nil {
+			return err
+		}
+		res.add(sub)
+	}
+	if ref, ok := m["$dynamicRef"].(string); ok {
+		target, err := v.s.resolveRef(ref, base)
+		if err != nil {
+			return err
+		}
+		target = v.dynamicTarget(ref, target)
+		sub, err := v.follow("$dynamicRef", ref, target, inst, path, schemaPath)
+		if err != nil {
+			return err
+		}
+		res.add(sub)
+	}
+	return nil
+}
+
+// follow 校验keyword引用的schema，同一个值再次进入正在校验的引用目标时报告循环引用的位置
+func (v *validator) follow(keyword string, ref string, target located, inst interface{}, path string, schemaPath string) (*result, types.ZfError) {
+	if key, ok := activeKey(target.schema, path); ok {
+		if v.active[key] {
+			return nil, schemaError(schemaPath+"/"+keyword, fmt.Sprintf("%s %s 存在循环引用，校验%s时没有消耗输入", keyword, ref, path))
+		}
+		v.active[key] = true
+		defer delete(v.active, key)
+	}
+	return v.validate(target.schema, target.base, inst, path, schemaPath+"/"+keyword)
+}
+
+// activeKey 返回object类型的schema和值的路径组成的键，boolean类型的schema不会循环
+func activeKey(schema interface{}, path string) (string, bool) {
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%p %s", m, path), true
+}
+
+// dynamicTarget 引用的schema包含同名的$dynamicAnchor时，使用动态作用域中最外层的同名$dynamicAnchor
+func (v *validator) dynamicTarget(ref string, target located) located {
+	i := strings.LastIndex(ref, "#")
+	if i < 0 {
+		return target
+	}
+	anchor := ref[i+1:]
+	if m, ok := target.schema.(map[string]interface{}); !ok || m["$dynamicAnchor"] != anchor {
+		return target
+	}
+	for _, scope := range v.scopes {
+		if found, ok := v.s.dynamicAnchors[scope+"#"+anchor]; ok {
+			return found
+		}
+	}
+	return target
+}
+
+// validateApplicators 校验allOf、anyOf、oneOf、not、if/then/else、dependentSchemas
+func (v *validator) validateApplicators(m map[string]interface{}, base string, inst interface{}, path string, schemaPath string, res *result) types.ZfError {
+	if items, ok := m["allOf"].([]interface{}); ok {
+		for i, item := range items {
+			sub, err := v.validate(item, base, inst, path, fmt.Sprintf("%s/allOf/%d", schemaPath, i))
+			if err != nil {
+				return err
+			}
+			res.add(sub)
+		}
+	}
+	if items, ok := m["anyOf"].([]interface{}); ok {
+		matched := false
+		for i, item := range items {
+			sub, err := v.validate(item, base, inst, path, fmt.Sprintf("%s/anyOf/%d", schemaPath, i))
+			if err != nil {
+				return err
+			}
+			if sub.valid() {
+				matched = true
+				res.annotate(sub)
+			}
+		}
+		if !matched {
+			res.fail(path, "anyOf", schemaPath+"/anyOf", "不满足anyOf中的任何一个schema")
+		}
+	}
+	if items, ok := m["oneOf"].([]interface{}); ok {
+		var matched []int
+		var last *result
+		for i, item := range items {
+			sub, err := v.validate(item, base, inst, path, fmt.Sprintf("%s/oneOf/%d", schemaPath, i))
+			if err != nil {
+				return err
+			}
+			if sub.valid() {
+				matched = append(matched, i)
+				last = sub
+			}
+		}
+		switch len(matched) {
+		case 0:
+			res.fail(path, "oneOf", schemaPath+"/oneOf", "不满足oneOf中的任何一个schema")
+		case 1:
+			res.annotate(last)
+		default:
+			res.fail(path, "oneOf", schemaPath+"/oneOf", "同时满足oneOf中第%s个schema，应只满足一个", joinInts(matched))
+		}
+	}
+	if not, ok := m["not"]; ok {
+		sub, err := v.validate(not, base, inst, path, schemaPath+"/not")
+		if err != nil {
+			return err
+		}
+		if sub.valid() {
+			res.fail(path, "not", schemaPath+"/not", "不应满足not中的schema")
+		}
+	}
+	if cond, ok := m["if"]; ok {
+		sub, err := v.validate(cond, base, inst, path, schemaPath+"/if")
+		if err != nil {
+			return err
+		}
+		branch := "else"
+		if sub.valid() {
+			res.annotate(sub)
+			branch = "then"
+		}
+		if next, ok := m[branch]; ok {
+			sub, err = v.validate(next, base, inst, path, schemaPath+"/"+branch)
+			if err != nil {
+				return err
+			}
+			res.add(sub)
+		}
+	}
+	if deps, ok := m["dependentSchemas"].(map[string]interface{}); ok {
+		if obj, ok := inst.(map[string]interface{}); ok {
+			for _, key := range sortedKeys(deps) {
+				if _, ok := obj[key]; !ok {
+					continue
+				}
+				sub, err := v.validate(deps[key], base, inst, path, schemaPath+"/dependentSchemas/"+escapePointer(key))
+				if err != nil {
+					return err
+				}
+				res.add(sub)
+			}
+		}
+	}
+	return nil
+}
+
+// validateGeneric 校验type、enum、const
+func (v *validator) validateGeneric(m map[string]interface{}, base string, inst interface{}, path string, schemaPath string, res *result) types.ZfError {
+	if t, ok := m["type"]; ok {
+		var names []string
+		switch val := t.(type) {
+		case string:
+			names = []string{val}
+		case []interface{}:
+			for _, item := range val {
+				if name, ok := item.(string); ok {
+					names = append(names, name)
+				}
+			}
+		}
+		actual := typeOf(inst)
+		matched := false
+		for _, name := range names {
+			if name == actual || name == "number" && actual == "integer" {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			res.fail(path, "type", schemaPath+"/type", "类型应为%s，实际为%s", strings.Join(names, "|"), actual)
+		}
+	}
+	if enum, ok := m["enum"].([]interface{}); ok {
+		matched := false
+		for _, item := range enum {
+			equal, err := cmd.Equal(inst, item)
+			if err != nil {
+				return err
+			}
+			if equal {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			res.fail(path, "enum", schemaPath+"/enum", "%s不是%s中的值", valueString(inst), valueString(enum))
+		}
+	}
+	if c, ok := m["const"]; ok {
+		equal, err := cmd.Equal(inst, c)
+		if err != nil {
+			return err
+		}
+		if !equal {
+			res.fail(path, "const", schemaPath+"/const", "值应为%s，实际为%s", valueString(c), valueString(inst))
+		}
+	}
+	return nil
+}
+
+// validateNumber 校验multipleOf、maximum、exclusiveMaximum、minimum、exclusiveMinimum
+func (v *validator) validateNumber(m map[string]interface{}, base string, inst interface{}, path string, schemaPath string, res *result) types.ZfError {
+	n, ok := numberRat(inst)
+	if !ok {
+		return nil
+	}
+	if d, ok := numberRat(m["multipleOf"]); ok && d.Sign() > 0 {
+		if !new(big.Rat).Quo(n, d).IsInt() {
+			res.fail(path, "multipleOf", schemaPath+"/multipleOf", "%s不是%s的倍数", valueString(inst), valueString(m["multipleOf"]))
+		}
+	}
+	for _, bound := range []struct {
+		keyword string
+		fails   func(c int) bool
+		message string
+	}{
+		{"maximum", func(c int) bool { return c > 0 }, "%s大于最大值%s"},
+		{"exclusiveMaximum", func(c int) bool { return c >= 0 }, "%s应小于%s"},
+		{"minimum", func(c int) bool { return c < 0 }, "%s小于最小值%s"},
+		{"exclusiveMinimum", func(c int) bool { return c <= 0 }, "%s应大于%s"},
+	} {
+		limit, ok := numberRat(m[bound.keyword])
+		if ok && bound.fails(n.Cmp(limit)) {
+			res.fail(path, bound.keyword, schemaPath+"/"+bound.keyword, bound.message, valueString(inst), valueString(m[bound.keyword]))
+		}
+	}
+	return nil
+}
+
+// validateString 校验maxLength、minLength、pattern、format，长度按unicode字符计算
+func (v *validator) validateString(m map[string]interface{}, base string, inst interface{}, path string, schemaPath string, res *result) types.ZfError {
+	s, ok := inst.(string)
+	if !ok {
+		return nil
+	}
+	length := utf8.RuneCountInString(s)
+	if max, ok := intKeyword(m, "maxLength"); ok && length > max {
+		res.fail(path, "maxLength", schemaPath+"/maxLength", "长度%d超过最大长度%d", length, max)
+	}
+	if min, ok := intKeyword(m, "minLength"); ok && length < min {
+		res.fail(path, "minLength", schemaPath+"/minLength", "长度%d小于最小长度%d", length, min)
+	}
+	if pattern, ok := m["pattern"].(string); ok && !v.s.patterns[pattern].MatchString(s) {
+		res.fail(path, "pattern", schemaPath+"/pattern", "%q不匹配正则%s", s, pattern)
+	}
+	if format, ok := m["format"].(string); ok && v.s.AssertFormat {
+		if valid, known := CheckFormat(format, s); known && !valid {
+			res.fail(path, "format", schemaPath+"/format", "%q不是合法的%s格式", s, format)
+		}
+	}
+	return nil
+}
+
+// validateArray 校验prefixItems、items、contains、maxItems、minItems、uniqueItems
+func (v *validator) validateArray(m map[string]interface{}, base string, inst interface{}, path string, schemaPath string, res *result) types.ZfError {
+	array, ok := inst.([]interface{})
+	if !ok {
+		return nil
+	}
+	prefix := 0
+	if items, ok := m["prefixItems"].([]interface{}); ok {
+		for i, item := range items {
+			if i >= len(array) {
+				break
+			}
+			sub, err := v.validate(item, base, array[i], util.IndexPath(path, i), fmt.Sprintf("%s/prefixItems/%d", schemaPath, i))
+			if err != nil {
+				return err
+			}
+			res.add(sub)
+			res.item(i)
+		}
+		prefix = len(items)
+	}
+	if items, ok := m["items"]; ok {
+		for i := prefix; i < len(array); i++ {
+			if b, ok := items.(bool); ok && !b {
+				res.fail(util.IndexPath(path, i), "items", schemaPath+"/items", "不允许多余的元素，最多%d个元素", prefix)
+				continue
+			}
+			sub, err := v.validate(items, base, array[i], util.IndexPath(path, i), schemaPath+"/items")
+			if err != nil {
+				return err
+			}
+			res.add(sub)
+		}
+		res.allItems = true
+	}
+	if contains, ok := m["contains"]; ok {
+		count := 0
+		for i, item := range array {
+			sub, err := v.validate(contains, base, item, util.IndexPath(path, i), schemaPath+"/contains")
+			if err != nil {
+				return err
+			}
+			if sub.valid() {
+				count++
+				res.item(i)
+			}
+		}
+		min, hasMin := intKeyword(m, "minContains")
+		if !hasMin {
+			min = 1
+		}
+		if count < min {
+			if hasMin {
+				res.fail(path, "minContains", schemaPath+"/minContains", "满足contains的元素有%d个，至少应有%d个", count, min)
+			} else {
+				res.fail(path, "contains", schemaPath+"/contains", "没有满足contains的元素")
+			}
+		}
+		if max, ok := intKeyword(m, "maxContains"); ok && count > max {
+			res.fail(path, "maxContains", schemaPath+"/maxContains", "满足contains的元素有%d个，最多应有%d个", count, max)
+		}
+	}
+	if max, ok := intKeyword(m, "maxItems"); ok && len(array) > max {
+		res.fail(path, "maxItems", schemaPath+"/maxItems", "元素个数%d超过最大值%d", len(array), max)
+	}
+	if min, ok := intKeyword(m, "minItems"); ok && len(array) < min {
+		res.fail(path, "minItems", schemaPath+"/minItems", "元素个数%d小于最小值%d", len(array), min)
+	}
+	if unique, _ := m["uniqueItems"].(bool); unique {
+	outer:
+		for i := 1; i < len(array); i++ {
+			for j := 0; j < i; j++ {
+				equal, err := cmd.Equal(array[i], array[j])
+				if err != nil {
+					return err
+				}
+				if equal {
+					res.fail(util.IndexPath(path, i), "uniqueItems", schemaPath+"/uniqueItems", "与%s重复", util.IndexPath(path, j))
+					continue outer
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateObject 校验properties、patternProperties、additionalProperties、propertyNames、required、dependentRequired、maxProperties、minProperties
+func (v *validator) validateObject(m map[string]interface{}, base string, inst interface{}, path string, schemaPath string, res *result) types.ZfError {
+	obj, ok := inst.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	keys := sortedKeys(obj)
+	properties, _ := m["properties"].(map[string]interface{})
+	patternProperties, _ := m["patternProperties"].(map[string]interface{})
+	patterns := sortedKeys(patternProperties)
+	additional, hasAdditional := m["additionalProperties"]
+	for _, key := range keys {
+		matched := false
+		if sub, ok := properties[key]; ok {
+			matched = true
+			if err := v.validateProperty(sub, base, obj[key], path, key, schemaPath+"/properties/"+escapePointer(key), "properties", res); err != nil {
+				return err
+			}
+		}
+		for _, pattern := range patterns {
+			if !v.s.patterns[pattern].MatchString(key) {
+				continue
+			}
+			matched = true
+			if err := v.validateProperty(patternProperties[pattern], base, obj[key], path, key, schemaPath+"/patternProperties/"+escapePointer(pattern), "patternProperties", res); err != nil {
+				return err
+			}
+		}
+		if !matched && hasAdditional {
+			if err := v.validateProperty(additional, base, obj[key], path, key, schemaPath+"/additionalProperties", "additionalProperties", res); err != nil {
+				return err
+			}
+		}
+	}
+	if names, ok := m["propertyNames"]; ok {
+		for _, key := range keys {
+			sub, err := v.validate(names, base, key, util.ChildPath(path, key), schemaPath+"/propertyNames")
+			if err != nil {
+				return err
+			}
+			if !sub.valid() {
+				res.fail(util.ChildPath(path, key), "propertyNames", schemaPath+"/propertyNames", "键名%q不满足propertyNames: %s", key, sub.violations[0].Message)
+			}
+		}
+	}
+	if required, ok := m["required"].([]interface{}); ok {
+		for _, item := range required {
+			key, _ := item.(string)
+			if _, ok := obj[key]; !ok {
+				res.fail(path, "required", schemaPath+"/required", "缺少必需的键%s", key)
+			}
+		}
+	}
+	if deps, ok := m["dependentRequired"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(deps) {
+			if _, ok := obj[key]; !ok {
+				continue
+			}
+			required, _ := deps[key].([]interface{})
+			for _, item := range required {
+				dep, _ := item.(string)
+				if _, ok := obj[dep]; !ok {
+					res.fail(path, "dependentRequired", schemaPath+"/dependentRequired/"+escapePointer(key), "存在键%s时缺少必需的键%s", key, dep)
+				}
+			}
+		}
+	}
+	if max, ok := intKeyword(m, "maxProperties"); ok && len(obj) > max {
+		res.fail(path, "maxProperties", schemaPath+"/maxProperties", "键的个数%d超过最大值%d", len(obj), max)
+	}
+	if min, ok := intKeyword(m, "minProperties"); ok && len(obj) < min {
+		res.fail(path, "minProperties", schemaPath+"/minProperties", "键的个数%d小于最小值%d", len(obj), min)
+	}
+	return nil
+}
+
+// validateProperty 按子schema校验object中的一个键，schema为false时说明不允许该键
+func (v *validator) validateProperty(schema interface{}, base string, value interface{}, path string, key string, schemaPath string, keyword string, res *result) types.ZfError {
+	res.prop(key)
+	if b, ok := schema.(bool); ok && !b {
+		res.fail(util.ChildPath(path, key), keyword, schemaPath, "不允许的键%s", key)
+		return nil
+	}
+	sub, err := v.validate(schema, base, value, util.ChildPath(path, key), schemaPath)
+	if err != nil {
+		return err
+	}
+	res.violations = append(res.violations, sub.violations...)
+	return nil
+}
+
+// validateUnevaluated 校验unevaluatedItems、unevaluatedProperties，只对其他关键字没有校验过的元素和键生效
+func (v *validator) validateUnevaluated(m map[string]interface{}, base string, inst interface{}, path string, schemaPath string, res *result) types.ZfError {
+	if schema, ok := m["unevaluatedItems"]; ok {
+		if array, ok := inst.([]interface{}); ok && !res.allItems {
+			for i, item := range array {
+				if res.items[i] {
+					continue
+				}
+				if b, ok := schema.(bool); ok && !b {
+					res.fail(util.IndexPath(path, i), "unevaluatedItems", schemaPath+"/unevaluatedItems", "不允许未校验的元素")
+					continue
+				}
+				sub, err := v.validate(schema, base, item, util.IndexPath(path, i), schemaPath+"/unevaluatedItems")
+				if err != nil {
+					return err
+				}
+				res.violations = append(res.violations, sub.violations...)
+			}
+			res.allItems = true
+		}
+	}
+	if schema, ok := m["unevaluatedProperties"]; ok {
+		if obj, ok := inst.(map[string]interface{}); ok {
+			for _, key := range sortedKeys(obj) {
+				if res.props[key] {
+					continue
+				}
+				if err := v.validateProperty(schema, base, obj[key], path, key, schemaPath+"/unevaluatedProperties", "unevaluatedProperties", res); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// typeOf 返回值的JSON Schema类型，数值为整数时返回integer
+func typeOf(v interface{}) string {
+	if r, ok := numberRat(v); ok {
+		if r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	t, err := types.GetType(v)
+	if err != nil {
+		return fmt.Sprintf("%T", v)
+	}
+	if t == types.Bool {
+		return "boolean"
+	}
+	return string(t)
+}
+
+// numberRat 将数字转换为精确的有理数，不是数字或为NaN、Inf时返回false
+func numberRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case nil, bool, string, map[string]interface{}, []interface{}:
+		return nil, false
+	case float64:
+		r := new(big.Rat).SetFloat64(n)
+		return r, r != nil
+	case float32:
+		return numberRat(float64(n))
+	}
+	if t, err := types.GetType(v); err != nil || t != types.Number {
+		return nil, false
+	}
+	return new(big.Rat).SetString(fmt.Sprint(v))
+}
+
+// intKeyword 返回值为非负整数的关键字，如 maxLength、minItems
+func intKeyword(m map[string]interface{}, keyword string) (int, bool) {
+	r, ok := numberRat(m[keyword])
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return int(r.Num().Int64()), true
+}
+
+func valueString(v interface{}) string {
+	opts := codec.DefaultMarshalOptions()
+	opts.Compact = true
+	opts.EscapeHTML = false
+	text, err := (&json.JSONCodec{}).MarshalWithOptions(v, opts)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(text)
+}
+
+func joinInts(values []int) string {
+	texts := make([]string, len(values))
+	for i, v := range values {
+		texts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(texts, "、")
+}
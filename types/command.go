@@ -9,6 +9,8 @@ type TypeCommand interface {
 	// GetCurrType path规则参考JSONPATH, .标最顶级
 	// 返回当前的类别名称
 	GetCurrType() string
+	// IsBinary 输出内容是否为二进制，二进制内容输出时不追加换行
+	IsBinary() bool
 	// Parse 格式化输出文本
 	Parse(text string) (string, ZfError)
 	// Marshal toString
@@ -19,8 +21,28 @@ type TypeCommand interface {
 	GetType(path string, text string) (ValueType, ZfError)
 	// GetValues 获取指定路径的值，如果类型是array，则支持指定顺序的值
 	GetValues(from uint, to uint, path string, text string) (interface{}, ZfError)
+	// ParseValue 按当前格式解析命令行传入的值
+	ParseValue(value string) (interface{}, ZfError)
 	// Append 对指定路径的值进行追加内容，如果类型是object，可以指定key增加键值对,返回更新后的值
 	Append(path string, key string, index uint, value string, text string) (string, ZfError)
+	// AppendParsed 同Append，value为已经解析好的值
+	AppendParsed(path string, key string, index uint, value interface{}, text string) (string, ZfError)
 	// SetValue 对指定路径的值进行覆盖更新，返回更新后的值
 	SetValue(path string, value string, text string) (string, ZfError)
+	// SetParsed 同SetValue，value为已经解析好的值，create为true时自动创建缺失的父节点
+	SetParsed(path string, value interface{}, create bool, text string) (string, ZfError)
+	// Rename 修改指定路径的键名，返回更新后的值
+	Rename(path string, newKey string, text string) (string, ZfError)
+	// Move 将from路径的值移动到to路径，返回更新后的值
+	Move(from string, to string, text string) (string, ZfError)
+	// Copy 将from路径的值复制到to路径，返回更新后的值
+	Copy(from string, to string, text string) (string, ZfError)
+	// Edit 解析一次文本后按顺序执行所有操作，任何一个操作失败时返回错误
+	Edit(ops []EditOp, text string) (string, ZfError)
+	// Sort 对指定路径的数组排序，路径中包含 [] 时对所有元素生效，返回更新后的值
+	Sort(path string, opts SortOptions, text string) (string, ZfError)
+	// Uniq 删除指定路径数组中的重复元素，by不为空时按元素中该路径的值判断，返回更新后的值
+	Uniq(path string, by string, text string) (string, ZfError)
+	// GetDocument 返回最近一次解析或修改后的整个文档
+	GetDocument() interface{}
 }
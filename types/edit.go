@@ -0,0 +1,27 @@
+package types
+
+func init() {
+
+}
+
+// EditOp edit命令中的一个操作
+type EditOp struct {
+	// Op 操作类型 set|append|delete|rename|move|copy|merge
+	Op string
+	// Path 操作的路径，move和copy为源路径
+	Path string
+	// To rename的新键名，move和copy的目标路径
+	To string
+	// Value set、append、merge使用的值
+	Value interface{}
+	// Key append到object时使用的键
+	Key string
+	// Index append到array时插入的位置
+	Index uint
+	// Create set时自动创建缺失的父节点
+	Create bool
+	// Array merge时数组的合并策略，为空时使用默认策略
+	Array string
+	// Null merge时null的处理方式，为空时使用默认方式
+	Null string
+}
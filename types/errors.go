@@ -73,3 +73,55 @@ func NewIndexOutOfBoundErrorFromMapSlice(array []map[string]interface{}, arrayNa
 func (err *IndexOutOfBoundError) Error() error {
 	return errors.New(fmt.Sprintf("数组%s越界，最大:%d，请求值:%d", err.arrayName, err.size, err.index))
 }
+
+// PatchError patch中某个操作执行失败，Index为操作在patch中的下标
+type PatchError struct {
+	Index int
+	Op    string
+	Path  string
+	Cause string
+}
+
+func NewPatchError(index int, op string, path string, cause string) *PatchError {
+	return &PatchError{Index: index, Op: op, Path: path, Cause: cause}
+}
+
+func (err *PatchError) Error() error {
+	return errors.New(fmt.Sprintf("patch[%d] (%s %s)执行失败: %s", err.Index, err.Op, err.Path, err.Cause))
+}
+
+// EditError edit中某个操作执行失败，Index为操作的下标
+type EditError struct {
+	Index int
+	Op    string
+	Path  string
+	Cause string
+}
+
+func NewEditError(index int, op string, path string, cause string) *EditError {
+	return &EditError{Index: index, Op: op, Path: path, Cause: cause}
+}
+
+func (err *EditError) Error() error {
+	return errors.New(fmt.Sprintf("edit[%d] (%s %s)执行失败: %s", err.Index, err.Op, err.Path, err.Cause))
+}
+
+// QueryError query表达式或sql语句执行失败
+type QueryError struct {
+	// Kind query或sql
+	Kind  string
+	Expr  string
+	Cause string
+}
+
+func NewQueryError(expr string, cause string) *QueryError {
+	return &QueryError{Kind: "query", Expr: expr, Cause: cause}
+}
+
+func NewSQLError(stmt string, cause string) *QueryError {
+	return &QueryError{Kind: "sql", Expr: stmt, Cause: cause}
+}
+
+func (err *QueryError) Error() error {
+	return errors.New(fmt.Sprintf("%s %s 执行失败: %s", err.Kind, err.Expr, err.Cause))
+}
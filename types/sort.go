@@ -0,0 +1,15 @@
+package types
+
+func init() {
+
+}
+
+// SortOptions sort命令的参数
+type SortOptions struct {
+	// By 数组元素为object时按该路径的值排序，如 .name，为空时按元素本身排序
+	By string
+	// Reverse 倒序排列，相同的元素保持原来的顺序
+	Reverse bool
+	// RecursiveKeys 路径为object时所有层级的object都按键排序，否则只排序该object的键
+	RecursiveKeys bool
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"reflect"
 )
 
@@ -27,7 +28,7 @@ func GetType(v interface{}) (ValueType, ZfError) {
 		return Array, nil
 	case string:
 		return String, nil
-	case byte, int, uint, int8, int16, uint16, int32, uint32, int64, uint64, float32, float64, complex64, complex128:
+	case byte, int, uint, int8, int16, uint16, int32, uint32, int64, uint64, float32, float64, complex64, complex128, json.Number:
 		return Number, nil
 	default:
 		return Null, NewUnSupportError(reflect.TypeOf(v).Name())
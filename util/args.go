@@ -4,12 +4,27 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"os"
+	"strings"
 )
 
 func init() {
 
 }
 
+// inputFile 从参数读取的输入文件路径
+var inputFile string
+
+// InputFile 返回从参数读取的输入文件路径，没有时返回空字符串
+// 用于解析输入内容中的相对路径，如hocon的include
+func InputFile() string {
+	return inputFile
+}
+
+// SetInputFile 设置输入文件路径，用于读取patch等辅助文件后恢复为输入文档的路径
+func SetInputFile(file string) {
+	inputFile = file
+}
+
 func ExactArgsWithPipe(n int) cobra.PositionalArgs {
 	return func(cmd *cobra.Command, args []string) error {
 		if IsPipe() {
@@ -39,5 +54,49 @@ func InitArgsFromPipe(args []string) ([]string, error) {
 		result = append(result, args...)
 		return result, nil
 	}
+	if len(args) > 0 {
+		// 参数是已存在的文件时读取文件内容，如 zf plist get Info.plist
+		content, ok, err := readArgFile(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result := make([]string, len(args))
+			copy(result, args)
+			result[0] = content
+			return result, nil
+		}
+	}
 	return args, nil
 }
+
+// readArgFile 如果参数是一个已存在的普通文件，返回其内容
+func readArgFile(arg string) (string, bool, error) {
+	if arg == "" || strings.Contains(arg, "\n") {
+		return "", false, nil
+	}
+	info, err := os.Stat(arg)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false, nil
+	}
+	content, err := ReadFile(arg)
+	if err != nil {
+		return "", false, err
+	}
+	return content, true, nil
+}
+
+// ReadFile 读取文件内容，并记录为当前输入文件
+func ReadFile(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	content, err := ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	inputFile = file
+	return content, nil
+}
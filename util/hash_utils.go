@@ -0,0 +1,47 @@
+package util
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/izern/zf/types"
+)
+
+func init() {
+
+}
+
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// HashAlgorithms 返回支持的摘要算法
+func HashAlgorithms() []string {
+	names := make([]string, 0, len(hashAlgorithms))
+	for name := range hashAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Digest 计算内容的摘要，返回十六进制字符串
+func Digest(algo string, data []byte) (string, types.ZfError) {
+	newHash, ok := hashAlgorithms[strings.ToLower(algo)]
+	if !ok {
+		return "", types.NewUnSupportError("不支持的摘要算法:" + algo + "，可选值为" + strings.Join(HashAlgorithms(), "|"))
+	}
+	h := newHash()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
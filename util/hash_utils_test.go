@@ -0,0 +1,23 @@
+package util
+
+import (
+	"github.com/stretchr/testify/assert"
+	testing2 "testing"
+)
+
+func init() {
+
+}
+
+func Test_Digest(t *testing2.T) {
+	digest, err := Digest("sha256", []byte("abc"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", digest)
+
+	digest, err = Digest("MD5", []byte(""))
+	assert.Nil(t, err)
+	assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", digest)
+
+	_, err = Digest("crc32", []byte("abc"))
+	assert.NotNil(t, err)
+}
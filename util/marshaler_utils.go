@@ -1,7 +1,9 @@
 package util
 
 import (
+	"encoding/base64"
 	"fmt"
+	"time"
 )
 
 func init() {
@@ -94,3 +96,69 @@ func IsConversionNeeded(v interface{}) bool {
 		return false
 	}
 }
+
+// NormalizeValue converts values decoded from binary formats into the plain
+// map[string]interface{}/[]interface{} tree used by the handlers.
+// Byte strings are encoded as base64 text and timestamps as RFC 3339 strings,
+// so that every text codec can represent them.
+func NormalizeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if b, ok := k.([]byte); ok {
+				k = string(b)
+			}
+			res[fmt.Sprint(k)] = NormalizeValue(item)
+		}
+		return res
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = NormalizeValue(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = NormalizeValue(item)
+		}
+		return val
+	case []byte:
+		return base64.StdEncoding.EncodeToString(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// DeepCopy 递归复制object和array，修改副本不会影响原值
+func DeepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			res[k] = DeepCopy(item)
+		}
+		return res
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(val))
+		for k, item := range val {
+			res[k] = DeepCopy(item)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = DeepCopy(item)
+		}
+		return res
+	case []map[string]interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = DeepCopy(item)
+		}
+		return res
+	default:
+		return v
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
 	"runtime"
 )
@@ -157,6 +158,8 @@ func EstimateMemoryUsage(v interface{}) int64 {
 		}
 	case string:
 		size += int64(len(val))
+	case json.Number:
+		size += int64(len(val))
 	case int, int32, int64, uint, uint32, uint64:
 		size += 8
 	case float32, float64:
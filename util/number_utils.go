@@ -1,5 +1,13 @@
 package util
 
+import (
+	"encoding/json"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
 func init() {
 
 }
@@ -17,3 +25,98 @@ func Max(x, y int) int {
 	}
 	return y
 }
+
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// IsNumber 文本是否为json格式的数字
+func IsNumber(text string) bool {
+	return numberPattern.MatchString(text)
+}
+
+// ExactNumber 将json.Number转换为int64、uint64或float64
+// 超出uint64范围的整数、float64无法精确表示的小数返回false
+func ExactNumber(n json.Number) (interface{}, bool) {
+	text := string(n)
+	if !strings.ContainsAny(text, ".eE") {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i, true
+		}
+		if u, err := strconv.ParseUint(text, 10, 64); err == nil {
+			return u, true
+		}
+		return nil, false
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, false
+	}
+	if f == 0 {
+		// 下溢的数字不能再用big.Rat比较，如 1e-100000000
+		if strings.Trim(strings.SplitN(strings.ToLower(text), "e", 2)[0], "-+0.") != "" {
+			return nil, false
+		}
+		return f, true
+	}
+	// 最短表示与原文的十进制值相同，说明float64输出时不会丢失精度
+	exact, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, false
+	}
+	shortest, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	if exact.Cmp(shortest) != 0 {
+		return nil, false
+	}
+	return f, true
+}
+
+// ParseNumber 解析json格式的数字文本，无法用go的数字类型精确表示时返回json.Number
+func ParseNumber(text string) interface{} {
+	n := json.Number(text)
+	if v, ok := ExactNumber(n); ok {
+		return v
+	}
+	return n
+}
+
+// MapNumbers 递归替换值中所有的json.Number
+func MapNumbers(v interface{}, fn func(json.Number) interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		return fn(val)
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			res[k] = MapNumbers(item, fn)
+		}
+		return res
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(val))
+		for k, item := range val {
+			res[k] = MapNumbers(item, fn)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = MapNumbers(item, fn)
+		}
+		return res
+	case []map[string]interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = MapNumbers(item, fn)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// NumberOrString 能精确表示的数字转换为go的数字类型，否则保留原文作为字符串
+// 用于不支持任意精度数字的格式
+func NumberOrString(n json.Number) interface{} {
+	if v, ok := ExactNumber(n); ok {
+		return v
+	}
+	return string(n)
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	testing2 "testing"
+)
+
+func init() {
+
+}
+
+func Test_ExactNumber(t *testing2.T) {
+	cases := map[string]interface{}{
+		"3":                        int64(3),
+		"-9007199254740993":        int64(-9007199254740993),
+		"18446744073709551615":     uint64(18446744073709551615),
+		"1.50":                     1.5,
+		"0.1":                      0.1,
+		"1e30":                     1e30,
+		"0.000":                    0.0,
+		"18446744073709551616":     nil,
+		"0.1000000000000000000001": nil,
+		"1e400":                    nil,
+		"1e-400":                   nil,
+	}
+	for text, expected := range cases {
+		v, ok := ExactNumber(json.Number(text))
+		if expected == nil {
+			assert.False(t, ok, text)
+			assert.Equal(t, json.Number(text), ParseNumber(text))
+			assert.Equal(t, text, NumberOrString(json.Number(text)))
+			continue
+		}
+		assert.True(t, ok, text)
+		assert.Equal(t, expected, v, text)
+	}
+}
+
+func Test_MapNumbers(t *testing2.T) {
+	v := MapNumbers(map[string]interface{}{
+		"a": json.Number("1"),
+		"b": []interface{}{json.Number("2.5"), "x"},
+	}, NumberOrString)
+	assert.Equal(t, map[string]interface{}{"a": int64(1), "b": []interface{}{2.5, "x"}}, v)
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/izern/zf/types"
+)
+
+func init() {
+
+}
+
+// PrintResult 输出处理结果到标准输出
+// 文本内容追加换行，二进制内容按原始字节输出
+func PrintResult(text string, binary bool) error {
+	return WriteResult(os.Stdout, text, binary)
+}
+
+// WriteResult 输出处理结果到指定的writer
+func WriteResult(w io.Writer, text string, binary bool) error {
+	if binary {
+		_, err := io.WriteString(w, text)
+		return err
+	}
+	_, err := fmt.Fprintln(w, text)
+	return err
+}
+
+// RawStrings 将值转换为适合shell脚本使用的文本
+// 字符串不加引号，数组每个元素一项，object和嵌套的数组输出为单行json
+func RawStrings(value interface{}) ([]string, types.ZfError) {
+	switch val := value.(type) {
+	case []interface{}:
+		result := make([]string, 0, len(val))
+		for _, item := range val {
+			s, err := rawString(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	case []map[string]interface{}:
+		result := make([]string, 0, len(val))
+		for _, item := range val {
+			s, err := rawString(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		s, err := rawString(value)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+func rawString(v interface{}) (string, types.ZfError) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case map[interface{}]interface{}:
+		return rawString(ConvertMap2String(val))
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if e := encoder.Encode(val); e != nil {
+			return "", types.NewFormatError(e.Error(), "json")
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+}
+
+// PrintRaw 逐行输出，nul为true时每项以NUL字符结尾，用于 xargs -0
+func PrintRaw(values []string, nul bool) error {
+	return WriteRaw(os.Stdout, values, nul)
+}
+
+// WriteRaw 逐行输出到指定的writer
+func WriteRaw(w io.Writer, values []string, nul bool) error {
+	separator := "\n"
+	if nul {
+		separator = "\x00"
+	}
+	for _, v := range values {
+		if _, err := io.WriteString(w, v+separator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package util
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	testing2 "testing"
+)
+
+func init() {
+
+}
+
+func Test_RawStrings(t *testing2.T) {
+	values, err := RawStrings("a b")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a b"}, values)
+
+	values, err = RawStrings([]interface{}{"x", 1, 2.5, true, nil, map[string]interface{}{"a": "<b>"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"x", "1", "2.5", "true", "null", `{"a":"<b>"}`}, values)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteRaw(&buf, []string{"a", "b c"}, true))
+	assert.Equal(t, "a\x00b c\x00", buf.String())
+	buf.Reset()
+	assert.Nil(t, WriteRaw(&buf, []string{"a", "b c"}, false))
+	assert.Equal(t, "a\nb c\n", buf.String())
+}
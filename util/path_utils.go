@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"github.com/izern/zf/types"
 	"math"
 	"strconv"
@@ -70,9 +71,9 @@ func parsePathNode(str string, isRoot bool) (*types.Path, types.ZfError) {
 		}, nil
 	}
 
-	// Find array/index notation
-	rangeStart := strings.Index(str, "[")
-	rangeEnd := strings.LastIndex(str, "]")
+	// Find array/index notation, \[ and \] are part of the key
+	rangeStart := indexUnescaped(str, '[')
+	rangeEnd := lastIndexUnescaped(str, ']')
 	
 	// Validate bracket pairing
 	if rangeStart != -1 && rangeEnd == -1 {
@@ -89,13 +90,13 @@ func parsePathNode(str string, isRoot bool) (*types.Path, types.ZfError) {
 	if rangeStart == -1 {
 		return &types.Path{
 			Type:        types.NormalNode,
-			NodeKey:     str,
+			NodeKey:     unescapeBrackets(str),
 			OriginValue: str,
 		}, nil
 	}
 
 	// Parse node with brackets
-	nodeKey := str[:rangeStart]
+	nodeKey := unescapeBrackets(str[:rangeStart])
 	rangeContent := str[rangeStart+1 : rangeEnd]
 
 	// Empty brackets [] - range all
@@ -204,8 +205,13 @@ func splitPath(path string) ([]string, types.ZfError) {
 		if strings.HasSuffix(part, "\\") && i < len(parts)-1 {
 			// This is an escaped dot, combine with next part
 			combined := part[:len(part)-1] + "." + parts[i+1]
-			result = append(result, combined)
 			i += 2 // Skip next part as it's already processed
+			// A key may contain several escaped dots, e.g. a\.b\.c
+			for strings.HasSuffix(combined, "\\") && i < len(parts) {
+				combined = combined[:len(combined)-1] + "." + parts[i]
+				i++
+			}
+			result = append(result, combined)
 		} else {
 			result = append(result, part)
 			i++
@@ -214,3 +220,71 @@ func splitPath(path string) ([]string, types.ZfError) {
 
 	return result, nil
 }
+
+// EscapePathKey escapes '.', '[' and ']' so that a key can be used as one path node, e.g. a.b -> a\.b
+func EscapePathKey(key string) string {
+	if !strings.ContainsAny(key, ".[]") {
+		return key
+	}
+	var b strings.Builder
+	for _, r := range key {
+		if r == '.' || r == '[' || r == ']' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// indexUnescaped returns the index of the first c that is not escaped by '\'
+func indexUnescaped(str string, c byte) int {
+	for i := 0; i < len(str); i++ {
+		if str[i] == c && (i == 0 || str[i-1] != '\\') {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastIndexUnescaped returns the index of the last c that is not escaped by '\'
+func lastIndexUnescaped(str string, c byte) int {
+	for i := len(str) - 1; i >= 0; i-- {
+		if str[i] == c && (i == 0 || str[i-1] != '\\') {
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeBrackets turns \[ and \] back into brackets
+func unescapeBrackets(key string) string {
+	if !strings.Contains(key, "\\") {
+		return key
+	}
+	return strings.NewReplacer("\\[", "[", "\\]", "]").Replace(key)
+}
+
+// ChildPath returns the path of key under the object at path, special characters in key are escaped
+func ChildPath(path string, key string) string {
+	key = EscapePathKey(key)
+	if path == "." {
+		return path + key
+	}
+	return path + "." + key
+}
+
+// ElementPath returns the path of any element of the array at path, e.g. .a[]
+func ElementPath(path string) string {
+	if path == "." {
+		return ".[]"
+	}
+	return path + "[]"
+}
+
+// IndexPath returns the path of the element at index of the array at path, e.g. .a[0]
+func IndexPath(path string, index int) string {
+	if path == "." {
+		return fmt.Sprintf(".[%d]", index)
+	}
+	return fmt.Sprintf("%s[%d]", path, index)
+}
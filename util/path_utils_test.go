@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"github.com/izern/zf/types"
 	testing2 "testing"
 )
 
@@ -20,3 +21,20 @@ func Test_ParsePath(t *testing2.T) {
 		fmt.Println(path)
 	}
 }
+
+func Test_EscapePathKey(t *testing2.T) {
+	for _, key := range []string{"a", "a.b", "a.b.c", ".a", "k[0]", "x]", "a.b[1]"} {
+		path := ".x." + EscapePathKey(key)
+		paths, zfError := ParsePath(path)
+		if zfError != nil {
+			t.Fatal(path, zfError.Error())
+		}
+		if len(paths) != 3 || paths[2].NodeKey != key || paths[2].Type != types.NormalNode {
+			t.Fatal(path, paths[2])
+		}
+	}
+	paths, zfError := ParsePath(`.a\.b[1]`)
+	if zfError != nil || len(paths) != 2 || paths[1].NodeKey != "a.b" || paths[1].Type != types.IndexNode || paths[1].Index != 1 {
+		t.Fatal(paths, zfError)
+	}
+}
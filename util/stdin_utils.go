@@ -1,8 +1,8 @@
 package util
 
 import (
-	"bufio"
 	"io"
+	"io/ioutil"
 	"os"
 )
 
@@ -17,21 +17,11 @@ func IsPipe() bool {
 	return isPipe
 }
 
-// ReadAll 从reader中读取所有内容，WARNING:
+// ReadAll 从reader中读取所有内容，内容按原始字节保存，二进制数据也不会被改写
 func ReadAll(r io.Reader) (string, error) {
-	reader := bufio.NewReader(r)
-	var data []byte
-	p := make([]byte, 1024)
-	for {
-		n, err := reader.Read(p)
-		if err != nil && err != io.EOF {
-			return "", err
-		} else if n == 0 {
-			break
-		} else {
-			data = append(data, p[:n]...)
-		}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
 	}
 	return string(data), nil
-
 }
@@ -0,0 +1,242 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/izern/zf/types"
+)
+
+func init() {
+
+}
+
+// TableStyle 表格输出样式
+type TableStyle string
+
+const (
+	ASCIITable    TableStyle = "table"
+	MarkdownTable TableStyle = "markdown"
+)
+
+// IsTableStyle 判断输出格式是否为表格
+func IsTableStyle(name string) bool {
+	return name == string(ASCIITable) || name == string(MarkdownTable)
+}
+
+// RenderTable 将object数组渲染为表格，每个object一行
+// columns为空时使用所有object的键(按字母排序)，嵌套的值以json格式显示在单元格中
+func RenderTable(value interface{}, columns []string, style TableStyle) (string, types.ZfError) {
+	rows, err := tableRows(value)
+	if err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		columns = tableColumns(rows)
+	}
+	if len(columns) == 0 {
+		return "", types.NewUnSupportError("没有可以输出的列")
+	}
+
+	cells := make([][]string, len(rows))
+	numeric := make([]bool, len(columns))
+	for i := range numeric {
+		numeric[i] = true
+	}
+	for i, row := range rows {
+		cells[i] = make([]string, len(columns))
+		for j, column := range columns {
+			v, ok := row[column]
+			if !ok {
+				continue
+			}
+			cell, cellErr := formatCell(v)
+			if cellErr != nil {
+				return "", cellErr
+			}
+			if t, _ := types.GetType(v); t != types.Number {
+				numeric[j] = false
+			}
+			cells[i][j] = escapeCell(cell, style)
+		}
+	}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = escapeCell(column, style)
+	}
+
+	widths := make([]int, len(columns))
+	for i, h := range header {
+		widths[i] = Max(DisplayWidth(h), 3)
+	}
+	for _, row := range cells {
+		for i, cell := range row {
+			widths[i] = Max(widths[i], DisplayWidth(cell))
+		}
+	}
+
+	var sb strings.Builder
+	if style == MarkdownTable {
+		writeTableRow(&sb, header, widths, nil)
+		sb.WriteString("|")
+		for i, w := range widths {
+			if numeric[i] && len(rows) > 0 {
+				sb.WriteString(" " + strings.Repeat("-", w-1) + ": |")
+			} else {
+				sb.WriteString(" " + strings.Repeat("-", w) + " |")
+			}
+		}
+		sb.WriteString("\n")
+		for _, row := range cells {
+			writeTableRow(&sb, row, widths, numeric)
+		}
+	} else {
+		border := tableBorder(widths)
+		sb.WriteString(border)
+		writeTableRow(&sb, header, widths, nil)
+		sb.WriteString(border)
+		for _, row := range cells {
+			writeTableRow(&sb, row, widths, numeric)
+		}
+		sb.WriteString(border)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// tableRows 数组中每个object为一行，单个object输出为一行
+func tableRows(value interface{}) ([]map[string]interface{}, types.ZfError) {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{val}, nil
+	case []map[string]interface{}:
+		return val, nil
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(val))
+		for i, item := range val {
+			switch row := item.(type) {
+			case map[string]interface{}:
+				rows = append(rows, row)
+			case map[interface{}]interface{}:
+				rows = append(rows, ConvertMap2String(row))
+			default:
+				itemType, _ := types.GetType(item)
+				return nil, types.NewUnSupportError(fmt.Sprintf("表格只支持object数组，第%d个元素类型为%s", i, itemType))
+			}
+		}
+		return rows, nil
+	default:
+		valueType, _ := types.GetType(value)
+		return nil, types.NewUnSupportError("表格只支持object数组，当前类型:" + string(valueType))
+	}
+}
+
+func tableColumns(rows []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	columns := make([]string, 0)
+	for _, row := range rows {
+		for k := range row {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func formatCell(v interface{}) (string, types.ZfError) {
+	switch val := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return val, nil
+	case map[string]interface{}, []interface{}, []map[string]interface{}:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if e := encoder.Encode(val); e != nil {
+			return "", types.NewFormatError(e.Error(), "json")
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	case map[interface{}]interface{}:
+		return formatCell(ConvertMap2String(val))
+	default:
+		return fmt.Sprint(val), nil
+	}
+}
+
+func escapeCell(cell string, style TableStyle) string {
+	if style == MarkdownTable {
+		cell = strings.Replace(cell, "|", "\\|", -1)
+		cell = strings.Replace(cell, "\r\n", "<br>", -1)
+		return strings.Replace(cell, "\n", "<br>", -1)
+	}
+	cell = strings.Replace(cell, "\r", "\\r", -1)
+	return strings.Replace(cell, "\n", "\\n", -1)
+}
+
+func tableBorder(widths []int) string {
+	var sb strings.Builder
+	sb.WriteString("+")
+	for _, w := range widths {
+		sb.WriteString(strings.Repeat("-", w+2))
+		sb.WriteString("+")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// writeTableRow 输出一行，数字列右对齐
+func writeTableRow(sb *strings.Builder, cells []string, widths []int, rightAlign []bool) {
+	sb.WriteString("|")
+	for i, cell := range cells {
+		padding := strings.Repeat(" ", widths[i]-DisplayWidth(cell))
+		if rightAlign != nil && rightAlign[i] {
+			sb.WriteString(" " + padding + cell + " |")
+		} else {
+			sb.WriteString(" " + cell + padding + " |")
+		}
+	}
+	sb.WriteString("\n")
+}
+
+// DisplayWidth 返回字符串在终端中的显示宽度，中文等宽字符占两列
+func DisplayWidth(s string) int {
+	width := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		width += runeWidth(r)
+	}
+	return width
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200d || (r >= 0xfe00 && r <= 0xfe0f) || (r >= 0x0300 && r <= 0x036f):
+		// 零宽连接符、变体选择符、组合字符
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0x303e,
+		r >= 0x3041 && r <= 0x33ff,
+		r >= 0x3400 && r <= 0x4dbf,
+		r >= 0x4e00 && r <= 0x9fff,
+		r >= 0xa000 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	default:
+		return 1
+	}
+}
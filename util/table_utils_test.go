@@ -0,0 +1,39 @@
+package util
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	testing2 "testing"
+)
+
+func init() {
+
+}
+
+func Test_RenderTable(t *testing2.T) {
+	rows := []interface{}{
+		map[string]interface{}{"name": "a|b", "port": 443, "opts": map[string]interface{}{"udp": true}},
+		map[string]interface{}{"name": "节点", "port": 8080},
+	}
+
+	table, err := RenderTable(rows, nil, ASCIITable)
+	assert.Nil(t, err)
+	fmt.Println(table)
+	assert.Equal(t, `+------+--------------+------+
+| name | opts         | port |
++------+--------------+------+
+| a|b  | {"udp":true} |  443 |
+| 节点 |              | 8080 |
++------+--------------+------+`, table)
+
+	table, err = RenderTable(rows, []string{"port", "name"}, MarkdownTable)
+	assert.Nil(t, err)
+	fmt.Println(table)
+	assert.Equal(t, `| port | name |
+| ---: | ---- |
+|  443 | a\|b |
+| 8080 | 节点 |`, table)
+
+	_, err = RenderTable([]interface{}{"a", "b"}, nil, ASCIITable)
+	assert.NotNil(t, err)
+}
@@ -2,8 +2,13 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"github.com/izern/zf/cmd"
 	_ "github.com/izern/zf/cmd"
+	"github.com/izern/zf/codec"
+	"github.com/izern/zf/codec/json"
+	"github.com/izern/zf/query"
+	"github.com/izern/zf/schema"
 	"github.com/izern/zf/types"
 	"github.com/izern/zf/util"
 	"github.com/spf13/cobra"
@@ -11,10 +16,18 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 )
 
 var pretty bool
 
+// outputFormat 全局的 -o/--output 参数，为空时与输入格式相同
+var outputFormat string
+
+// marshalOptions 全局的输出排版参数，对所有格式生效
+var marshalOptions = codec.DefaultMarshalOptions()
+var quoteStyle string
+
 func init() {
 	// Optimize for performance
 	runtime.GOMAXPROCS(runtime.NumCPU())
@@ -26,7 +39,19 @@ func main() {
 		Short:   "zf用来解析格式化字符串文本",
 		Example: "cat file.yml | zf yaml ",
 		Version: "v0.9.1", // Updated version
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			return applyMarshalOptions()
+		},
 	}
+	flags := rootCmd.PersistentFlags()
+	flags.IntVar(&marshalOptions.Indent, "indent", 0, "缩进空格数，默认使用各格式的缩进(json不缩进，yaml为4，hocon为2)")
+	flags.BoolVar(&marshalOptions.Tab, "tab", false, "使用tab缩进，yaml不支持tab缩进")
+	flags.BoolVar(&marshalOptions.Compact, "compact", false, "紧凑输出，yaml使用flow风格，toml使用inline table")
+	flags.BoolVar(&marshalOptions.SortKeys, "sort-keys", false, "object按键排序输出，当前所有格式默认已按键排序")
+	flags.BoolVar(&marshalOptions.EscapeHTML, "escape-html", true, "json字符串中转义<、>、&")
+	flags.IntVar(&marshalOptions.FlowLevel, "flow-level", -1, "yaml从第几层嵌套开始使用flow风格，0为整个文档，-1不使用")
+	flags.BoolVar(&marshalOptions.Canonical, "canonical", false, "json按RFC 8785规范输出，键排序且数字和字符串格式固定，用于计算摘要和签名")
+	flags.StringVar(&quoteStyle, "quote-style", "", "yaml字符串值的引号风格 (single|double)，默认只在需要时加引号")
 
 	// Remove the separate version command since cobra handles it automatically
 	rootCmd.SetVersionTemplate("zf version: {{.Version}}\n")
@@ -53,10 +78,10 @@ func main() {
 				if err != nil {
 					return err.Error()
 				}
-				fmt.Println(text)
-				return nil
+				return printDocument(typeCmd, text)
 			},
 		}
+		cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "输出格式，支持所有已注册的格式，默认与输入格式相同；get还支持table|markdown")
 		rootCmd.AddCommand(cmd)
 		appendChildCmd(cmd, typeCmd)
 	}
@@ -142,18 +167,20 @@ func main() {
 				if e != nil {
 					return e.Error()
 				}
-				fmt.Println(text)
+				return util.PrintResult(text, toCmd.IsBinary())
 			}
 			
 			return nil
 		},
 	}
-	convertCmd.Flags().StringVarP(&from, "from", "f", "", "源数据格式 (json|yaml|toml)")
-	convertCmd.Flags().StringVarP(&to, "to", "t", "", "目标数据格式 (json|yaml|toml)")
+	convertCmd.Flags().StringVarP(&from, "from", "f", "", "源数据格式 (json|yaml|toml|msgpack|cbor|plist|bplist|hocon)")
+	convertCmd.Flags().StringVarP(&to, "to", "t", "", "目标数据格式 (json|yaml|toml|msgpack|cbor|plist|bplist|hocon)")
 	convertCmd.MarkFlagRequired("from")
 	convertCmd.MarkFlagRequired("to")
 
 	rootCmd.AddCommand(convertCmd)
+	appendMergeCmd(rootCmd)
+	appendDiffCmd(rootCmd)
 
 	// Add performance tuning command
 	perfCmd := &cobra.Command{
@@ -196,6 +223,269 @@ func main() {
 	}
 }
 
+// applyMarshalOptions 校验排版参数并应用到所有格式
+func applyMarshalOptions() error {
+	if marshalOptions.Indent < 0 {
+		return fmt.Errorf("--indent不能小于0: %d", marshalOptions.Indent)
+	}
+	if marshalOptions.Compact && (marshalOptions.Indent > 0 || marshalOptions.Tab) {
+		return fmt.Errorf("--compact不能与--indent、--tab同时使用")
+	}
+	if marshalOptions.Canonical && (marshalOptions.Indent > 0 || marshalOptions.Tab) {
+		return fmt.Errorf("--canonical不能与--indent、--tab同时使用")
+	}
+	switch codec.QuoteStyle(quoteStyle) {
+	case codec.DefaultQuote, codec.SingleQuote, codec.DoubleQuote:
+		marshalOptions.QuoteStyle = codec.QuoteStyle(quoteStyle)
+	default:
+		return fmt.Errorf("不支持的--quote-style: %s，可选值为single|double", quoteStyle)
+	}
+	cmd.SetMarshalOptions(marshalOptions)
+	return nil
+}
+
+// getOutputCmd 返回 -o 指定格式的处理器，未指定时使用输入格式
+func getOutputCmd(typeCmd types.TypeCommand) (types.TypeCommand, error) {
+	if outputFormat == "" || outputFormat == typeCmd.GetCurrType() {
+		return typeCmd, nil
+	}
+	return cmd.GetCmd(outputFormat)
+}
+
+// printValue 按输出格式序列化并输出值
+func printValue(typeCmd types.TypeCommand, value interface{}) error {
+	outputCmd, err := getOutputCmd(typeCmd)
+	if err != nil {
+		return err
+	}
+	text, zfError := outputCmd.Marshal(value)
+	if zfError != nil {
+		return zfError.Error()
+	}
+	return util.PrintResult(text, outputCmd.IsBinary())
+}
+
+// printDocument 输出处理后的整个文档，未指定输出格式时直接输出text
+func printDocument(typeCmd types.TypeCommand, text string) error {
+	if outputFormat == "" || outputFormat == typeCmd.GetCurrType() {
+		return util.PrintResult(text, typeCmd.IsBinary())
+	}
+	return printValue(typeCmd, typeCmd.GetDocument())
+}
+
+// readDocument 读取并解析文件，format为空时按扩展名识别格式，文件为 - 时从标准输入读取
+func readDocument(file string, format string) (interface{}, types.TypeCommand, error) {
+	var typeCmd types.TypeCommand
+	var err error
+	if format != "" {
+		typeCmd, err = cmd.GetCmd(format)
+	} else if file == "-" {
+		err = fmt.Errorf("从标准输入读取时需要指定格式")
+	} else {
+		typeCmd, err = cmd.GetCmdByFile(file)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var text string
+	if file == "-" {
+		text, err = util.ReadAll(os.Stdin)
+	} else {
+		text, err = util.ReadFile(file)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	value, zfError := typeCmd.GetValues(0, math.MaxUint32, ".", text)
+	if zfError != nil {
+		return nil, nil, fmt.Errorf("%s: %v", file, zfError.Error())
+	}
+	return value, typeCmd, nil
+}
+
+// readSideDocument 同readDocument，用于读取patch等辅助文件
+// 辅助文件中的相对路径相对于辅助文件，读取后恢复输入文件路径，输入文档中的相对路径(如hocon的include)仍相对于输入文件或当前目录
+func readSideDocument(file string, format string) (interface{}, error) {
+	inputFile := util.InputFile()
+	defer util.SetInputFile(inputFile)
+	value, _, err := readDocument(file, format)
+	return value, err
+}
+
+func appendMergeCmd(rootCmd *cobra.Command) {
+	var from, output, array, null string
+	var strategies []string
+	c := &cobra.Command{
+		Use:   "merge",
+		Short: "深度合并多个文档",
+		Long: `按顺序将后面的文档深度合并到第一个文档中，文件可以是任意已注册的格式，按扩展名识别
+object按键递归合并，数组按策略合并，其他类型使用后面文档的值`,
+		Example: "zf merge values.yaml values-prod.yaml override.json --strategy .proxies=merge:name",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			opts := cmd.DefaultMergeOptions()
+			opts.Array = array
+			opts.Null = null
+			opts.Paths = make(map[string]string, len(strategies))
+			for _, s := range strategies {
+				i := strings.LastIndex(s, "=")
+				if i <= 0 {
+					return fmt.Errorf("--strategy格式应为<path>=<strategy>: %s", s)
+				}
+				opts.Paths[s[:i]] = s[i+1:]
+			}
+			if err := opts.Validate(); err != nil {
+				return err.Error()
+			}
+
+			var result interface{}
+			var outputCmd types.TypeCommand
+			for i, file := range args {
+				value, typeCmd, err := readDocument(file, from)
+				if err != nil {
+					return err
+				}
+				if i == 0 {
+					result, outputCmd = value, typeCmd
+					continue
+				}
+				merged, zfError := cmd.Merge(result, value, opts)
+				if zfError != nil {
+					return fmt.Errorf("%s: %v", file, zfError.Error())
+				}
+				result = merged
+			}
+			if output != "" {
+				var err error
+				if outputCmd, err = cmd.GetCmd(output); err != nil {
+					return err
+				}
+			}
+			text, zfError := outputCmd.Marshal(result)
+			if zfError != nil {
+				return zfError.Error()
+			}
+			return util.PrintResult(text, outputCmd.IsBinary())
+		},
+	}
+	c.Flags().StringVarP(&from, "from", "f", "", "所有文件的格式，默认按扩展名识别，文件为 - 时从标准输入读取")
+	c.Flags().StringVarP(&output, "output", "o", "", "输出格式，默认与第一个文件相同")
+	c.Flags().StringVar(&array, "array", cmd.ArrayReplace, "数组合并策略 (replace|append|unique|merge|merge:<key>)")
+	c.Flags().StringArrayVar(&strategies, "strategy", nil, "指定路径的数组合并策略，如 .proxies=merge:name，路径中的[]匹配所有元素，不能使用下标，可重复指定")
+	c.Flags().StringVar(&null, "null", cmd.NullSet, "值为null时的处理方式 (set|delete|ignore)，delete删除对应的键")
+	rootCmd.AddCommand(c)
+}
+
+func appendDiffCmd(rootCmd *cobra.Command) {
+	var from, output string
+	var quiet, patch, mergePatch bool
+	c := &cobra.Command{
+		Use:   "diff",
+		Short: "按语义比较两个文档",
+		Long: `比较两个文档的内容，忽略排版、键顺序和格式之间的表示差异，如yaml与json中的同一数字
+输出新增(+)、删除(-)、修改(~)的路径，数组按下标比较
+--patch 输出RFC 6902 JSON Patch，可以通过 patch 子命令应用到文档
+--merge-patch 输出RFC 7396 JSON Merge Patch，可以通过 merge-patch 子命令应用到文档
+内容相同时退出码为0，不同时为1，出错时为2`,
+		Example: "zf diff config.yaml config.json -o json",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(c *cobra.Command, args []string) error {
+			if patch && mergePatch {
+				return fmt.Errorf("--patch不能与--merge-patch同时使用")
+			}
+			different, err := runDiff(args[0], args[1], from, output, quiet, patch, mergePatch)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(2)
+			}
+			if different {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	c.Flags().StringVarP(&from, "from", "f", "", "两个文件的格式，默认按扩展名识别，文件为 - 时从标准输入读取")
+	c.Flags().StringVarP(&output, "output", "o", "", "按指定格式输出差异列表，如json，默认按行输出")
+	c.Flags().BoolVarP(&quiet, "quiet", "q", false, "不输出差异，只通过退出码表示是否相同")
+	c.Flags().BoolVar(&patch, "patch", false, "输出RFC 6902 JSON Patch，默认按json输出")
+	c.Flags().BoolVar(&mergePatch, "merge-patch", false, "输出RFC 7396 JSON Merge Patch，默认按json输出")
+	rootCmd.AddCommand(c)
+}
+
+// runDiff 比较两个文件并输出差异，返回是否存在差异
+func runDiff(fileA, fileB, from, output string, quiet, patch, mergePatch bool) (bool, error) {
+	a, _, err := readDocument(fileA, from)
+	if err != nil {
+		return false, err
+	}
+	b, _, err := readDocument(fileB, from)
+	if err != nil {
+		return false, err
+	}
+	if patch {
+		ops, zfError := cmd.DiffPatch(a, b)
+		if zfError != nil {
+			return false, zfError.Error()
+		}
+		if quiet {
+			return len(ops) > 0, nil
+		}
+		if output == "" {
+			output = "json"
+		}
+		return len(ops) > 0, printFormat(output, ops)
+	}
+	if mergePatch {
+		different, zfError := cmd.Equal(a, b)
+		if zfError != nil {
+			return false, zfError.Error()
+		}
+		different = !different
+		merge, zfError := cmd.DiffMergePatch(a, b)
+		if zfError != nil {
+			return false, zfError.Error()
+		}
+		if quiet {
+			return different, nil
+		}
+		if output == "" {
+			output = "json"
+		}
+		return different, printFormat(output, merge)
+	}
+	changes, zfError := cmd.Diff(a, b)
+	if zfError != nil {
+		return false, zfError.Error()
+	}
+	if quiet {
+		return len(changes) > 0, nil
+	}
+	if output == "" {
+		for _, change := range changes {
+			fmt.Println(change.String())
+		}
+		return len(changes) > 0, nil
+	}
+	values := make([]interface{}, len(changes))
+	for i, change := range changes {
+		values[i] = change.ToValue()
+	}
+	return len(changes) > 0, printFormat(output, values)
+}
+
+// printFormat 按指定格式序列化并输出值
+func printFormat(format string, value interface{}) error {
+	outputCmd, err := cmd.GetCmd(format)
+	if err != nil {
+		return err
+	}
+	text, zfError := outputCmd.Marshal(value)
+	if zfError != nil {
+		return zfError.Error()
+	}
+	return util.PrintResult(text, outputCmd.IsBinary())
+}
+
 func appendChildCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 	appendParseCmd(cmd, typeCmd)
 	appendGetTypeCmd(cmd, typeCmd)
@@ -203,6 +493,21 @@ func appendChildCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 	appendAppendCmd(cmd, typeCmd)
 	appendGetValueCmd(cmd, typeCmd)
 	appendSetValueCmd(cmd, typeCmd)
+	appendHashCmd(cmd, typeCmd)
+	appendRenameCmd(cmd, typeCmd)
+	appendMoveCmd(cmd, typeCmd)
+	appendCopyCmd(cmd, typeCmd)
+	appendPatchCmd(cmd, typeCmd)
+	appendMergePatchCmd(cmd, typeCmd)
+	appendEditCmd(cmd, typeCmd)
+	appendQueryCmd(cmd, typeCmd)
+	appendSQLCmd(cmd, typeCmd)
+	appendStatsCmd(cmd, typeCmd)
+	appendGrepCmd(cmd, typeCmd)
+	appendSortCmd(cmd, typeCmd)
+	appendUniqCmd(cmd, typeCmd)
+	appendValidateCmd(cmd, typeCmd)
+	appendSchemaCmd(cmd, typeCmd)
 }
 
 func appendGetTypeCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
@@ -222,6 +527,9 @@ func appendGetTypeCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 			if err != nil {
 				return err.Error()
 			}
+			if outputFormat != "" {
+				return printValue(typeCmd, string(typeStr))
+			}
 			fmt.Println(typeStr)
 			return nil
 		},
@@ -244,8 +552,7 @@ func appendParseCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 			if err != nil {
 				return err.Error()
 			}
-			fmt.Println(text)
-			return nil
+			return printDocument(typeCmd, text)
 		},
 	}
 	cmd.AddCommand(c)
@@ -267,6 +574,13 @@ func appendKeysCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 			if err != nil {
 				return err.Error()
 			}
+			if outputFormat != "" {
+				list := make([]interface{}, len(keys))
+				for i, key := range keys {
+					list[i] = key
+				}
+				return printValue(typeCmd, list)
+			}
 			for _, key := range keys {
 				fmt.Println(key)
 			}
@@ -283,10 +597,13 @@ func appendKeysCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 func appendGetValueCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 	var from, to uint
 	var path string
+	var columns []string
+	var raw, nul bool
 	c := &cobra.Command{
-		Use:   "get",
-		Short: "获取值",
-		Args:  util.ExactArgsWithPipe(1),
+		Use:     "get",
+		Short:   "获取值",
+		Example: "cat test.yml | zf yaml get -p .proxies -o table --columns name,type,port",
+		Args:    util.ExactArgsWithPipe(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			args, e := util.InitArgsFromPipe(args)
 			if e != nil {
@@ -296,24 +613,38 @@ func appendGetValueCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 			if err != nil {
 				return err.Error()
 			}
-			marshal, zfError := typeCmd.Marshal(res)
-			if zfError != nil {
-				return zfError.Error()
+			if raw || nul {
+				values, zfError := util.RawStrings(res)
+				if zfError != nil {
+					return zfError.Error()
+				}
+				return util.PrintRaw(values, nul)
 			}
-			fmt.Println(marshal)
-			return nil
+			if util.IsTableStyle(outputFormat) {
+				table, zfError := util.RenderTable(res, columns, util.TableStyle(outputFormat))
+				if zfError != nil {
+					return zfError.Error()
+				}
+				fmt.Println(table)
+				return nil
+			}
+			return printValue(typeCmd, res)
 		},
 	}
 	c.Flags().UintVarP(&from, "from", "f", 0, "范围起始值from")
 	c.Flags().UintVarP(&to, "to", "t", math.MaxInt16, "范围终止值to")
 	c.Flags().StringVarP(&path, "path", "p", ".", "节点路径，jsonpath格式")
+	c.Flags().StringSliceVar(&columns, "columns", nil, "-o table|markdown时输出的列，默认为所有键")
+	c.Flags().BoolVarP(&raw, "raw", "r", false, "原样输出，字符串不加引号，数组每行输出一个元素")
+	c.Flags().BoolVar(&nul, "nul", false, "同--raw，但使用NUL字符分隔，用于 xargs -0")
 
 	cmd.AddCommand(c)
 }
 
 func appendAppendCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
-	var key, value, path string
+	var key, path string
 	var index uint
+	input := &valueInput{}
 	c := &cobra.Command{
 		Use:   "append",
 		Short: "追加值",
@@ -323,24 +654,24 @@ func appendAppendCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 			if e != nil {
 				return e
 			}
-			text, err := typeCmd.Append(path, key, index, value, args[0])
+			text, err := input.append(typeCmd, path, key, index, args[0])
 			if err != nil {
-				return err.Error()
+				return err
 			}
-			fmt.Println(text)
-			return nil
+			return printDocument(typeCmd, text)
 		},
 	}
 	c.Flags().StringVarP(&path, "path", "p", ".", "节点路径，jsonpath格式")
 	c.Flags().UintVarP(&index, "index", "i", math.MaxInt16, "array或string时可以指定，默认插在最后面")
 	c.Flags().StringVarP(&key, "key", "k", "", "当类型为object时需指定key")
-	c.Flags().StringVarP(&value, "value", "v", "", "append的值")
-	c.MarkFlagRequired("value")
+	input.addFlags(c, "append的值")
 	cmd.AddCommand(c)
 }
 
 func appendSetValueCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
-	var path, value string
+	var path string
+	var create bool
+	input := &valueInput{}
 
 	c := &cobra.Command{
 		Use:   "set",
@@ -351,17 +682,842 @@ func appendSetValueCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
 			if e != nil {
 				return e
 			}
-			text, err := typeCmd.SetValue(path, value, args[0])
+			text, err := input.set(typeCmd, path, create, args[0])
+			if err != nil {
+				return err
+			}
+			return printDocument(typeCmd, text)
+		},
+	}
+	c.Flags().StringVarP(&path, "path", "p", ".", "节点路径，jsonpath格式")
+	input.addFlags(c, "set的值")
+	c.Flags().BoolVarP(&create, "create", "c", false, "自动创建缺失的父节点，下一级为下标时创建array")
+
+	cmd.AddCommand(c)
+}
+
+func appendHashCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var path, algo string
+	c := &cobra.Command{
+		Use:     "hash",
+		Short:   "计算指定路径值的摘要",
+		Long:    "按RFC 8785规范化为json后计算摘要，内容相同的yaml、toml、json等文档摘要相同",
+		Example: "cat test.yml | zf yaml hash -p .proxies[0] --algo sha256",
+		Args:    util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			res, err := typeCmd.GetValues(0, math.MaxUint32, path, args[0])
+			if err != nil {
+				return err.Error()
+			}
+			canonical, err := json.Canonicalize(res)
+			if err != nil {
+				return err.Error()
+			}
+			digest, err := util.Digest(algo, canonical)
 			if err != nil {
 				return err.Error()
 			}
-			fmt.Println(text)
+			fmt.Println(digest)
 			return nil
 		},
 	}
 	c.Flags().StringVarP(&path, "path", "p", ".", "节点路径，jsonpath格式")
-	c.Flags().StringVarP(&value, "value", "v", "", "set的值")
-	c.MarkFlagRequired("value")
+	c.Flags().StringVar(&algo, "algo", "sha256", "摘要算法 ("+strings.Join(util.HashAlgorithms(), "|")+")")
+	cmd.AddCommand(c)
+}
+
+func appendRenameCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var path, to string
+	c := &cobra.Command{
+		Use:     "rename",
+		Short:   "修改键名",
+		Example: "cat test.yml | zf yaml rename -p .proxies[].cipher --to method",
+		Args:    util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			text, err := typeCmd.Rename(path, to, args[0])
+			if err != nil {
+				return err.Error()
+			}
+			return printDocument(typeCmd, text)
+		},
+	}
+	c.Flags().StringVarP(&path, "path", "p", ".", "要修改的键的路径，jsonpath格式")
+	c.Flags().StringVarP(&to, "to", "t", "", "新的键名")
+	c.MarkFlagRequired("path")
+	c.MarkFlagRequired("to")
+	cmd.AddCommand(c)
+}
+
+func appendMoveCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var from, to string
+	c := &cobra.Command{
+		Use:     "move",
+		Short:   "移动值到新的路径",
+		Example: "cat test.yml | zf yaml move --from .a.b --to .c.d",
+		Args:    util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			text, err := typeCmd.Move(from, to, args[0])
+			if err != nil {
+				return err.Error()
+			}
+			return printDocument(typeCmd, text)
+		},
+	}
+	c.Flags().StringVarP(&from, "from", "f", "", "源路径，jsonpath格式")
+	c.Flags().StringVarP(&to, "to", "t", "", "目标路径，父节点不存在时自动创建")
+	c.MarkFlagRequired("from")
+	c.MarkFlagRequired("to")
+	cmd.AddCommand(c)
+}
+
+func appendCopyCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var from, to string
+	c := &cobra.Command{
+		Use:     "copy",
+		Short:   "复制值到新的路径",
+		Example: "cat test.yml | zf yaml copy --from .proxies[0] --to .backup.proxy",
+		Args:    util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			text, err := typeCmd.Copy(from, to, args[0])
+			if err != nil {
+				return err.Error()
+			}
+			return printDocument(typeCmd, text)
+		},
+	}
+	c.Flags().StringVarP(&from, "from", "f", "", "源路径，jsonpath格式")
+	c.Flags().StringVarP(&to, "to", "t", "", "目标路径，父节点不存在时自动创建")
+	c.MarkFlagRequired("from")
+	c.MarkFlagRequired("to")
+	cmd.AddCommand(c)
+}
+
+func appendPatchCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var patchFile, patchFormat string
+	c := &cobra.Command{
+		Use:   "patch",
+		Short: "应用RFC 6902 JSON Patch",
+		Long: `按顺序执行patch中的add、remove、replace、move、copy、test操作，任何一个操作失败时不输出内容
+patch文件可以是任意已注册的格式，按扩展名识别，可以通过 zf diff --patch 生成`,
+		Example: "cat test.yml | zf yaml patch --patch ops.json",
+		Args:    util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			patch, e := readSideDocument(patchFile, patchFormat)
+			if e != nil {
+				return e
+			}
+			args, e = util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			return applyDocumentPatch(typeCmd, args[0], patch)
+		},
+	}
+	c.Flags().StringVar(&patchFile, "patch", "", "patch文件路径")
+	c.Flags().StringVar(&patchFormat, "patch-format", "", "patch文件的格式，默认按扩展名识别")
+	c.MarkFlagRequired("patch")
+	cmd.AddCommand(c)
+}
+
+func appendMergePatchCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var patchFile, patchFormat string
+	c := &cobra.Command{
+		Use:   "merge-patch",
+		Short: "应用RFC 7396 JSON Merge Patch",
+		Long: `patch中的null删除对应的键，object递归合并，数组等其他值直接替换
+patch文件可以是任意已注册的格式，按扩展名识别，可以通过 zf diff --merge-patch 生成`,
+		Example: "cat test.yml | zf yaml merge-patch --patch overlay.yaml",
+		Args:    util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			patch, e := readSideDocument(patchFile, patchFormat)
+			if e != nil {
+				return e
+			}
+			args, e = util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			return applyDocumentMergePatch(typeCmd, args[0], patch)
+		},
+	}
+	c.Flags().StringVar(&patchFile, "patch", "", "merge patch文件路径")
+	c.Flags().StringVar(&patchFormat, "patch-format", "", "patch文件的格式，默认按扩展名识别")
+	c.MarkFlagRequired("patch")
+	cmd.AddCommand(c)
+}
+
+func appendEditCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var scriptFile, scriptFormat string
+	var expressions []string
+	var create bool
+	c := &cobra.Command{
+		Use:   "edit",
+		Short: "按顺序执行多个修改操作",
+		Long: `只解析和输出一次，按顺序执行set、append、delete、rename、move、copy、merge操作，任何一个操作失败时不输出内容
+脚本文件为操作的数组，可以是任意已注册的格式，如 [{op: set, path: .a, value: 1}, {op: move, from: .a, to: .b}]
+-e 表达式的格式为 set <path> <value>、append <path> <value>、delete <path>、rename <path> <新键名>、
+move <from> <to>、copy <from> <to>、merge <path> <value>，值按当前格式解析；脚本中的操作先于 -e 执行`,
+		Example: "cat test.yml | zf yaml edit -e 'set .port 1234' -e 'delete .rules[0]' -e 'rename .mode run-mode'",
+		Args:    util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ops, e := readEditOps(typeCmd, scriptFile, scriptFormat, expressions, create)
+			if e != nil {
+				return e
+			}
+			args, e = util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			text, err := typeCmd.Edit(ops, args[0])
+			if err != nil {
+				return err.Error()
+			}
+			return printDocument(typeCmd, text)
+		},
+	}
+	c.Flags().StringVarP(&scriptFile, "script", "s", "", "edit脚本文件路径")
+	c.Flags().StringVar(&scriptFormat, "script-format", "", "脚本文件的格式，默认按扩展名识别")
+	c.Flags().StringArrayVarP(&expressions, "expression", "e", nil, "edit表达式，如 'set .a 1'，可重复指定")
+	c.Flags().BoolVarP(&create, "create", "c", false, "set时自动创建缺失的父节点，脚本中指定了create的操作除外")
+	cmd.AddCommand(c)
+}
+
+// readEditOps 读取脚本和表达式中的操作
+func readEditOps(typeCmd types.TypeCommand, scriptFile, scriptFormat string, expressions []string, create bool) ([]types.EditOp, error) {
+	var ops []types.EditOp
+	if scriptFile != "" {
+		script, err := readSideDocument(scriptFile, scriptFormat)
+		if err != nil {
+			return nil, err
+		}
+		scriptOps, zfError := cmd.ParseEditScript(script, create)
+		if zfError != nil {
+			return nil, zfError.Error()
+		}
+		ops = append(ops, scriptOps...)
+	}
+	for _, expr := range expressions {
+		op, zfError := cmd.ParseEditExpression(expr, typeCmd)
+		if zfError != nil {
+			return nil, zfError.Error()
+		}
+		op.Create = create
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("需要通过--script或-e指定操作")
+	}
+	return ops, nil
+}
+
+func appendQueryCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var raw, nul bool
+	c := &cobra.Command{
+		Use:   "query <expr>",
+		Short: "使用管道表达式查询和转换文档",
+		Long: `表达式语法为jq的子集，每个结果单独输出
+支持 . .a .[0] .[] .[1:3] .. | , // and or not 算术和比较运算、{a: .b} [..] 构造、
+if/elif/else/end、as $x、reduce，以及 length keys map select sort_by group_by unique add
+to_entries with_entries split join test sub gsub 等函数`,
+		Example: `cat test.yml | zf yaml query '.proxies | map(select(.port > 1000)) | sort_by(.name)'
+zf yaml query test.yml '.proxies | group_by(.type) | map({type: .[0].type, count: length})'`,
+		Args: util.ExactArgsWithPipe(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			q, zfError := query.Compile(args[len(args)-1])
+			if zfError != nil {
+				return zfError.Error()
+			}
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			return runQuery(typeCmd, q, args[0], raw, nul)
+		},
+	}
+	c.Flags().BoolVarP(&raw, "raw", "r", false, "原样输出，字符串结果不加引号")
+	c.Flags().BoolVar(&nul, "nul", false, "同--raw，但使用NUL字符分隔，用于 xargs -0")
+	cmd.AddCommand(c)
+}
+
+// runQuery 解析文档并执行query，逐个输出结果
+func runQuery(typeCmd types.TypeCommand, q *query.Query, text string, raw, nul bool) error {
+	doc, zfError := typeCmd.GetValues(0, math.MaxUint32, ".", text)
+	if zfError != nil {
+		return zfError.Error()
+	}
+	results, zfError := q.Run(doc)
+	if zfError != nil {
+		return zfError.Error()
+	}
+	if raw || nul {
+		values, zfError := util.RawStrings(results)
+		if zfError != nil {
+			return zfError.Error()
+		}
+		return util.PrintRaw(values, nul)
+	}
+	outputCmd, e := getOutputCmd(typeCmd)
+	if e != nil {
+		return e
+	}
+	for _, result := range results {
+		// null结果也需要输出，如查询不存在的键
+		text, zfError := cmd.MarshalResult(outputCmd, result)
+		if zfError != nil {
+			return zfError.Error()
+		}
+		if e := util.PrintResult(text, outputCmd.IsBinary()); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func appendSQLCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	c := &cobra.Command{
+		Use:   "sql <statement>",
+		Short: "使用sql查询object数组",
+		Long: `SELECT 列 FROM 路径 [WHERE 条件] [GROUP BY 表达式] [HAVING 条件] [ORDER BY 表达式 [ASC|DESC]] [LIMIT n [OFFSET m]]
+FROM后为jsonpath格式的路径，路径下的值应为object数组；列名中的.表示嵌套的键，包含特殊字符的列名使用"或`+"`"+`包围，字符串使用'包围
+支持 = != <> < <= > >= AND OR NOT IS [NOT] NULL [NOT] IN [NOT] LIKE + - * / %，
+聚合函数 COUNT SUM AVG MIN MAX，以及 LOWER UPPER LENGTH COALESCE`,
+		Example: `cat test.yml | zf yaml sql "SELECT name, port FROM .proxies WHERE type = 'ss' ORDER BY port"
+zf yaml sql test.yml "SELECT type, COUNT(*) AS n FROM .proxies GROUP BY type ORDER BY n DESC" -o table`,
+		Args: util.ExactArgsWithPipe(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stmt, zfError := query.CompileSQL(args[len(args)-1])
+			if zfError != nil {
+				return zfError.Error()
+			}
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			return runSQL(typeCmd, stmt, args[0])
+		},
+	}
+	cmd.AddCommand(c)
+}
+
+// runSQL 取出FROM路径下的值执行sql，-o table|markdown时按表格输出
+func runSQL(typeCmd types.TypeCommand, stmt *query.SQL, text string) error {
+	rows, zfError := typeCmd.GetValues(0, math.MaxUint32, stmt.From, text)
+	if zfError != nil {
+		return zfError.Error()
+	}
+	res, zfError := stmt.Run(rows)
+	if zfError != nil {
+		return zfError.Error()
+	}
+	if util.IsTableStyle(outputFormat) {
+		table, zfError := util.RenderTable(res, stmt.Columns(), util.TableStyle(outputFormat))
+		if zfError != nil {
+			return zfError.Error()
+		}
+		fmt.Println(table)
+		return nil
+	}
+	return printValue(typeCmd, res)
+}
+
+func appendStatsCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var path, groupBy string
+	var distinct bool
+	c := &cobra.Command{
+		Use:   "stats",
+		Short: "统计路径下的值",
+		Long: `路径下的值为数组时统计其中的元素，路径中可以使用 [] 选取所有元素，如 .proxies[].port
+默认输出个数(count)、各类型的个数(types)、不同值的个数(distinct)，以及其中数字的和(sum)、平均值(avg)、最小值(min)、最大值(max)
+--group-by 按元素中键的值分组计数，--distinct 输出所有不同的值`,
+		Example: `cat test.yml | zf yaml stats -p .proxies[].port
+zf yaml stats test.yml -p .proxies --group-by type -o table
+zf yaml stats test.yml -p .proxies[].type --distinct`,
+		Args: util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if groupBy != "" && distinct {
+				return fmt.Errorf("--group-by不能与--distinct同时使用")
+			}
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			return runStats(typeCmd, path, groupBy, distinct, args[0])
+		},
+	}
+	c.Flags().StringVarP(&path, "path", "p", ".", "节点路径，jsonpath格式")
+	c.Flags().StringVar(&groupBy, "group-by", "", "按元素中键的值分组计数，嵌套的键用.分隔，为 . 时按元素本身分组")
+	c.Flags().BoolVar(&distinct, "distinct", false, "按第一次出现的顺序输出所有不同的值")
+	cmd.AddCommand(c)
+}
+
+// runStats 统计路径下的值并输出，-o table|markdown时按表格输出
+func runStats(typeCmd types.TypeCommand, path, groupBy string, distinct bool, text string) error {
+	res, zfError := typeCmd.GetValues(0, math.MaxUint32, path, text)
+	if zfError != nil {
+		return zfError.Error()
+	}
+	values := cmd.StatsValues(res)
+	var result interface{}
+	var columns []string
+	switch {
+	case distinct:
+		if result, zfError = cmd.Distinct(values); zfError != nil {
+			return zfError.Error()
+		}
+	case groupBy != "":
+		groups, zfError := cmd.GroupCounts(values, groupBy)
+		if zfError != nil {
+			return zfError.Error()
+		}
+		rows := make([]interface{}, len(groups))
+		for i, group := range groups {
+			rows[i] = group.ToValue()
+		}
+		result, columns = rows, []string{"value", "count"}
+	default:
+		stats, zfError := cmd.ComputeStats(values)
+		if zfError != nil {
+			return zfError.Error()
+		}
+		result = stats.ToValue()
+	}
+	if util.IsTableStyle(outputFormat) {
+		table, zfError := util.RenderTable(result, columns, util.TableStyle(outputFormat))
+		if zfError != nil {
+			return zfError.Error()
+		}
+		fmt.Println(table)
+		return nil
+	}
+	return printValue(typeCmd, result)
+}
+
+func appendGrepCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var keys, values, ignoreCase, pathsOnly bool
+	var typeNames []string
+	c := &cobra.Command{
+		Use:   "grep <regex>",
+		Short: "按正则搜索键名或值",
+		Long: `遍历整个文档，按行输出键名或值匹配正则的路径和值，默认同时匹配键名和值
+数字、bool、null按json文本匹配，指定 -o 时按该格式输出 path、value 的列表
+没有匹配时退出码为1`,
+		Example: `cat test.yml | zf yaml grep 'tcpbbr\.net'
+zf yaml grep test.yml --keys -i '^server'
+zf yaml grep test.yml --values --type number --paths-only '^443$'`,
+		Args: util.ExactArgsWithPipe(2),
+		RunE: func(c *cobra.Command, args []string) error {
+			if keys && values {
+				return fmt.Errorf("--keys不能与--values同时使用")
+			}
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			found, e := runGrep(typeCmd, args[len(args)-1], newGrepOptions(keys, values, ignoreCase, typeNames), pathsOnly, args[0])
+			if e != nil {
+				return e
+			}
+			if !found {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	c.Flags().BoolVar(&keys, "keys", false, "只匹配键名")
+	c.Flags().BoolVar(&values, "values", false, "只匹配值")
+	c.Flags().BoolVarP(&ignoreCase, "ignore-case", "i", false, "忽略大小写")
+	c.Flags().StringSliceVar(&typeNames, "type", nil, "只输出指定类型的值 (string|number|bool|null|object|array)，可以用逗号分隔多个")
+	c.Flags().BoolVar(&pathsOnly, "paths-only", false, "只输出路径")
+	cmd.AddCommand(c)
+}
+
+func newGrepOptions(keys, values, ignoreCase bool, typeNames []string) cmd.GrepOptions {
+	opts := cmd.GrepOptions{Keys: keys, Values: values, IgnoreCase: ignoreCase}
+	for _, name := range typeNames {
+		opts.Types = append(opts.Types, types.ValueType(strings.ToLower(name)))
+	}
+	return opts
+}
+
+// runGrep 解析文档并输出匹配的路径，返回是否有匹配
+func runGrep(typeCmd types.TypeCommand, pattern string, opts cmd.GrepOptions, pathsOnly bool, text string) (bool, error) {
+	doc, zfError := typeCmd.GetValues(0, math.MaxUint32, ".", text)
+	if zfError != nil {
+		return false, zfError.Error()
+	}
+	matches, zfError := cmd.Grep(doc, pattern, opts)
+	if zfError != nil {
+		return false, zfError.Error()
+	}
+	if outputFormat != "" {
+		values := make([]interface{}, len(matches))
+		for i, match := range matches {
+			if pathsOnly {
+				values[i] = match.Path
+			} else {
+				values[i] = match.ToValue()
+			}
+		}
+		if util.IsTableStyle(outputFormat) {
+			table, zfError := util.RenderTable(values, []string{"path", "value"}, util.TableStyle(outputFormat))
+			if zfError != nil {
+				return false, zfError.Error()
+			}
+			fmt.Println(table)
+			return len(matches) > 0, nil
+		}
+		return len(matches) > 0, printValue(typeCmd, values)
+	}
+	for _, match := range matches {
+		if pathsOnly {
+			fmt.Println(match.Path)
+		} else {
+			fmt.Println(match.String())
+		}
+	}
+	return len(matches) > 0, nil
+}
+
+func appendSortCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var path string
+	var opts types.SortOptions
+	c := &cobra.Command{
+		Use:   "sort",
+		Short: "对数组或object的键排序",
+		Long: `对路径下的数组稳定排序，路径中包含 [] 时对所有元素生效
+不同类型按 null < bool < number < string < array < object 排列，数字按数值比较
+路径为object时按键排序，--recursive-keys 时包括所有层级的object以及数组中的object`,
+		Example: `cat test.yml | zf yaml sort -p .rules
+zf yaml sort test.yml -p .proxies --by .name --reverse
+zf yaml sort test.yml --recursive-keys`,
+		Args: util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			enableSortKeys()
+			text, err := typeCmd.Sort(path, opts, args[0])
+			if err != nil {
+				return err.Error()
+			}
+			return printDocument(typeCmd, text)
+		},
+	}
+	c.Flags().StringVarP(&path, "path", "p", ".", "要排序的数组或object的路径，jsonpath格式")
+	c.Flags().StringVar(&opts.By, "by", "", "数组元素为object时按该路径的值排序，如 .name、.meta.region")
+	c.Flags().BoolVarP(&opts.Reverse, "reverse", "r", false, "倒序排列")
+	c.Flags().BoolVar(&opts.RecursiveKeys, "recursive-keys", false, "路径为object时所有层级的object都按键排序")
+	cmd.AddCommand(c)
+}
+
+// enableSortKeys 输出时object按键排序
+func enableSortKeys() {
+	marshalOptions.SortKeys = true
+	cmd.SetMarshalOptions(marshalOptions)
+}
+
+func appendUniqCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var path, by string
+	c := &cobra.Command{
+		Use:   "uniq",
+		Short: "删除数组中的重复元素",
+		Long: `删除路径下数组中的重复元素，保留第一次出现的元素，路径中包含 [] 时对所有元素生效
+数字按数值比较，object和array按内容比较，--by 按元素中该路径的值判断是否重复`,
+		Example: `cat test.yml | zf yaml uniq -p .rules
+zf yaml uniq test.yml -p .proxies --by .name`,
+		Args: util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args, e := util.InitArgsFromPipe(args)
+			if e != nil {
+				return e
+			}
+			text, err := typeCmd.Uniq(path, by, args[0])
+			if err != nil {
+				return err.Error()
+			}
+			return printDocument(typeCmd, text)
+		},
+	}
+	c.Flags().StringVarP(&path, "path", "p", ".", "数组的路径，jsonpath格式")
+	c.Flags().StringVar(&by, "by", "", "按元素中该路径的值判断是否重复，如 .name")
+	c.MarkFlagRequired("path")
+	cmd.AddCommand(c)
+}
 
+func appendValidateCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	var schemaFile, schemaFormat string
+	var assertFormat bool
+	c := &cobra.Command{
+		Use:   "validate",
+		Short: "按JSON Schema校验文档",
+		Long: `按JSON Schema draft 2020-12校验文档，按行输出所有不满足schema的路径、原因和失败的关键字在schema中的位置
+schema文件可以是任意已注册的格式，按扩展名识别，$ref只支持文档内部的引用，如 #/$defs/port
+format默认只作为注解，--assert-format 时校验date-time、date、time、email、hostname、ipv4、ipv6、uri、uuid等格式
+指定 -o 时按该格式输出 path、keyword、schemaPath、message 的列表
+校验通过时退出码为0，不通过时为1，出错时为2`,
+		Example: `cat test.yml | zf yaml validate --schema schema.json
+zf yaml validate test.yml --schema schema.yaml -o json`,
+		Args: util.ExactArgsWithPipe(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			valid, e := runValidate(typeCmd, schemaFile, schemaFormat, assertFormat, args)
+			if e != nil {
+				fmt.Fprintln(os.Stderr, e.Error())
+				os.Exit(2)
+			}
+			if !valid {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	c.Flags().StringVar(&schemaFile, "schema", "", "schema文件路径")
+	c.Flags().StringVar(&schemaFormat, "schema-format", "", "schema文件的格式，默认按扩展名识别")
+	c.Flags().BoolVar(&assertFormat, "assert-format", false, "校验format关键字")
+	c.MarkFlagRequired("schema")
 	cmd.AddCommand(c)
 }
+
+// runValidate 按schema校验文档并输出不满足的路径，返回文档是否满足schema
+func runValidate(typeCmd types.TypeCommand, schemaFile, schemaFormat string, assertFormat bool, args []string) (bool, error) {
+	value, e := readSideDocument(schemaFile, schemaFormat)
+	if e != nil {
+		return false, e
+	}
+	s, zfError := schema.Compile(value)
+	if zfError != nil {
+		return false, zfError.Error()
+	}
+	s.AssertFormat = assertFormat
+	args, e = util.InitArgsFromPipe(args)
+	if e != nil {
+		return false, e
+	}
+	doc, zfError := typeCmd.GetValues(0, math.MaxUint32, ".", args[0])
+	if zfError != nil {
+		return false, zfError.Error()
+	}
+	violations, zfError := s.Validate(doc)
+	if zfError != nil {
+		return false, zfError.Error()
+	}
+	if outputFormat != "" {
+		values := make([]interface{}, len(violations))
+		for i, violation := range violations {
+			values[i] = violation.ToValue()
+		}
+		if util.IsTableStyle(outputFormat) {
+			table, zfError := util.RenderTable(values, []string{"path", "keyword", "message", "schemaPath"}, util.TableStyle(outputFormat))
+			if zfError != nil {
+				return false, zfError.Error()
+			}
+			fmt.Println(table)
+			return len(violations) == 0, nil
+		}
+		return len(violations) == 0, printValue(typeCmd, values)
+	}
+	for _, violation := range violations {
+		fmt.Println(violation.String())
+	}
+	return len(violations) == 0, nil
+}
+
+func appendSchemaCmd(cmd *cobra.Command, typeCmd types.TypeCommand) {
+	c := &cobra.Command{
+		Use:   "schema",
+		Short: "JSON Schema相关的命令",
+	}
+	var maxEnum int
+	infer := &cobra.Command{
+		Use:   "infer [file...]",
+		Short: "由样本文档推断JSON Schema",
+		Long: `由一个或多个样本文档推断JSON Schema draft 2020-12，文件按当前格式解析，没有指定文件或文件为 - 时从标准输入读取
+推断类型、所有样本都包含的键(required)、数组元素的schema(items)，以及date-time、date、time、ipv4、ipv6、email、uuid、uri格式
+字符串的不同值不超过 --max-enum 个且每个值平均至少出现两次时生成enum
+默认按当前格式输出，可以通过 -o 指定格式，生成的schema可以用于 validate 子命令`,
+		Example: `zf yaml schema infer prod.yaml test.yaml -o json > schema.json
+cat test.yml | zf yaml schema infer --max-enum 0`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSchemaInfer(typeCmd, args, maxEnum)
+		},
+	}
+	infer.Flags().IntVar(&maxEnum, "max-enum", 10, "生成enum时最多的不同值个数，为0时不生成enum")
+	c.AddCommand(infer)
+	cmd.AddCommand(c)
+}
+
+// runSchemaInfer 读取并解析所有样本文档，输出推断的schema
+func runSchemaInfer(typeCmd types.TypeCommand, files []string, maxEnum int) error {
+	if len(files) == 0 {
+		if !util.IsPipe() {
+			return fmt.Errorf("需要指定样本文件或从标准输入读取")
+		}
+		files = []string{"-"}
+	}
+	docs := make([]interface{}, len(files))
+	for i, file := range files {
+		var text string
+		var e error
+		if file == "-" {
+			text, e = util.ReadAll(os.Stdin)
+		} else {
+			text, e = util.ReadFile(file)
+		}
+		if e != nil {
+			return e
+		}
+		doc, zfError := typeCmd.GetValues(0, math.MaxUint32, ".", text)
+		if zfError != nil {
+			return fmt.Errorf("%s: %v", file, zfError.Error())
+		}
+		docs[i] = doc
+	}
+	return printValue(typeCmd, schema.Infer(docs, schema.InferOptions{MaxEnum: maxEnum}))
+}
+
+// applyDocumentMergePatch 解析文档并应用JSON Merge Patch后输出
+func applyDocumentMergePatch(typeCmd types.TypeCommand, text string, patch interface{}) error {
+	doc, err := typeCmd.GetValues(0, math.MaxUint32, ".", text)
+	if err != nil {
+		return err.Error()
+	}
+	return printValue(typeCmd, cmd.MergePatch(doc, patch))
+}
+
+// applyDocumentPatch 解析文档并应用JSON Patch后输出
+func applyDocumentPatch(typeCmd types.TypeCommand, text string, patch interface{}) error {
+	doc, err := typeCmd.GetValues(0, math.MaxUint32, ".", text)
+	if err != nil {
+		return err.Error()
+	}
+	result, err := cmd.ApplyPatch(doc, patch)
+	if err != nil {
+		return err.Error()
+	}
+	return printValue(typeCmd, result)
+}
+
+// valueInput set和append的值参数，值可以来自-v、文件或环境变量，并可以指定类型
+type valueInput struct {
+	value, file, env string
+	types            map[string]*bool
+	c                *cobra.Command
+}
+
+func (in *valueInput) addFlags(c *cobra.Command, usage string) {
+	in.c = c
+	c.Flags().StringVarP(&in.value, "value", "v", "", usage+"，默认按当前格式解析")
+	c.Flags().StringVar(&in.file, "value-file", "", "从文件读取值，内容原样使用")
+	c.Flags().StringVar(&in.env, "value-env", "", "从环境变量读取值")
+	in.types = map[string]*bool{
+		cmd.ValueString: c.Flags().Bool(cmd.ValueString, false, "值作为字符串，不做解析"),
+		cmd.ValueInt:    c.Flags().Bool(cmd.ValueInt, false, "值作为整数"),
+		cmd.ValueFloat:  c.Flags().Bool(cmd.ValueFloat, false, "值作为小数"),
+		cmd.ValueBool:   c.Flags().Bool(cmd.ValueBool, false, "值作为bool，支持true|false|1|0"),
+		cmd.ValueNull:   c.Flags().Bool(cmd.ValueNull, false, "值为null，无需指定值"),
+		cmd.ValueJSON:   c.Flags().Bool(cmd.ValueJSON, false, "值按json解析"),
+	}
+}
+
+// read 返回值的文本和指定的类型
+func (in *valueInput) read() (string, string, error) {
+	valueType := cmd.ValueAuto
+	for _, name := range []string{cmd.ValueString, cmd.ValueInt, cmd.ValueFloat, cmd.ValueBool, cmd.ValueNull, cmd.ValueJSON} {
+		if !*in.types[name] {
+			continue
+		}
+		if valueType != cmd.ValueAuto {
+			return "", "", fmt.Errorf("--%s与--%s不能同时使用", valueType, name)
+		}
+		valueType = name
+	}
+
+	sources := 0
+	for _, name := range []string{"value", "value-file", "value-env"} {
+		if in.c.Flags().Changed(name) {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", "", fmt.Errorf("-v、--value-file、--value-env只能指定一个")
+	}
+	if sources == 0 && valueType != cmd.ValueNull {
+		return "", "", fmt.Errorf("需要通过-v、--value-file或--value-env指定值")
+	}
+
+	switch {
+	case in.c.Flags().Changed("value-file"):
+		content, e := ioutil.ReadFile(in.file)
+		if e != nil {
+			return "", "", e
+		}
+		return string(content), valueType, nil
+	case in.c.Flags().Changed("value-env"):
+		env, ok := os.LookupEnv(in.env)
+		if !ok {
+			return "", "", fmt.Errorf("环境变量%s不存在", in.env)
+		}
+		return env, valueType, nil
+	default:
+		return in.value, valueType, nil
+	}
+}
+
+// parse 读取并解析值，未指定类型时按当前格式解析
+func (in *valueInput) parse(typeCmd types.TypeCommand) (interface{}, error) {
+	value, valueType, e := in.read()
+	if e != nil {
+		return nil, e
+	}
+	var v interface{}
+	var err types.ZfError
+	if valueType == cmd.ValueAuto {
+		v, err = typeCmd.ParseValue(value)
+	} else {
+		v, err = cmd.ParseTypedValue(value, valueType)
+	}
+	if err != nil {
+		return nil, err.Error()
+	}
+	return v, nil
+}
+
+func (in *valueInput) append(typeCmd types.TypeCommand, path, key string, index uint, text string) (string, error) {
+	v, e := in.parse(typeCmd)
+	if e != nil {
+		return "", e
+	}
+	result, err := typeCmd.AppendParsed(path, key, index, v, text)
+	if err != nil {
+		return "", err.Error()
+	}
+	return result, nil
+}
+
+func (in *valueInput) set(typeCmd types.TypeCommand, path string, create bool, text string) (string, error) {
+	v, e := in.parse(typeCmd)
+	if e != nil {
+		return "", e
+	}
+	result, err := typeCmd.SetParsed(path, v, create, text)
+	if err != nil {
+		return "", err.Error()
+	}
+	return result, nil
+}